@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/git"
+	"github.com/randilt/git-commit-linter/internal/linter"
+	"github.com/randilt/git-commit-linter/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	commitDryRun bool
+
+	commitCmd = &cobra.Command{
+		Use:   "commit [-- <git commit options>]",
+		Short: "Compose a commit message step by step and commit",
+		Long: `Asks for the type, scope, subject, body, breaking change and issue
+references of a commit, and runs git commit with the message. Types and
+scopes are picked with the arrow keys from the config, with the
+descriptions from the keywords file; the type, scope and issue key guessed
+from the branch name and the staged files are preselected. The body may
+span several lines and ends at an empty line. The message is linted as it
+is typed, with the same rules as lint-file.
+
+Options after -- are passed to git commit.`,
+		Example: `  git-commit-linter commit
+  git-commit-linter commit -- --all`,
+		RunE: composeCommit,
+	}
+)
+
+func init() {
+	commitCmd.Flags().BoolVar(&commitDryRun, "dry-run", false, "print the message instead of committing")
+	rootCmd.AddCommand(commitCmd)
+}
+
+func composeCommit(cmd *cobra.Command, args []string) error {
+	l, repo, err := newRangeLinter()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	term, err := ui.NewTerminal()
+	if err != nil {
+		return fmt.Errorf("commit asks its questions on a terminal: %w", err)
+	}
+
+	branch, _ := repo.CurrentBranch()
+	c, err := composeMessage(term, l, l.ComposeDefaults(branch))
+	if errors.Is(err, ui.ErrInterrupted) {
+		ui.Warning("Commit cancelled")
+		// 130 is the status of a command stopped by Ctrl-C
+		os.Exit(130)
+	}
+	if err != nil {
+		return err
+	}
+
+	message := c.Message()
+	ui.Section("Commit Message")
+	ui.CodeBlock(message)
+	if commitDryRun {
+		return nil
+	}
+	ok, err := term.Confirm("Commit with this message?", true)
+	if errors.Is(err, ui.ErrInterrupted) || (err == nil && !ok) {
+		ui.Warning("Commit cancelled")
+		os.Exit(130)
+	}
+	if err != nil {
+		return err
+	}
+	return git.CommitWithMessage(message, args)
+}
+
+// composeMessage asks for each part of the message in turn, starting from
+// the defaults. Every text answer is linted with the answers before it.
+func composeMessage(term *ui.Terminal, l *linter.Linter, c linter.Composition) (linter.Composition, error) {
+	types := l.TypeOptions()
+	selected, err := term.Select("Type", types, optionIndex(types, c.Type))
+	if err != nil {
+		return c, err
+	}
+	c.Type = types[selected].Value
+
+	if scopes := l.ScopeOptions(); scopes != nil {
+		optional := !l.ScopeRequired()
+		if optional {
+			scopes = append([]ui.Option{{Value: "(none)", Description: "No scope"}}, scopes...)
+		}
+		selected, err := term.Select("Scope", scopes, optionIndex(scopes, c.Scope))
+		if err != nil {
+			return c, err
+		}
+		c.Scope = scopes[selected].Value
+		if optional && selected == 0 {
+			c.Scope = ""
+		}
+	} else {
+		hint := "optional"
+		if l.ScopeRequired() {
+			hint = "required"
+		}
+		c.Scope, err = term.Input("Scope", c.Scope, func(scope string) (string, error) {
+			if strings.TrimSpace(scope) == "" && l.ScopeRequired() {
+				return "", errors.New("a scope is required")
+			}
+			return hint, nil
+		})
+		if err != nil {
+			return c, err
+		}
+		c.Scope = strings.TrimSpace(c.Scope)
+	}
+
+	c.Subject, err = term.Input("Subject", c.Subject, func(subject string) (string, error) {
+		draft := c
+		draft.Subject = subject
+		if strings.TrimSpace(subject) == "" {
+			return "", errors.New("describe the change in a few words")
+		}
+		length := len(strings.TrimSpace(subject))
+		hint := fmt.Sprintf("%d characters", length)
+		if limit := l.MaxSubjectLength(); limit > 0 {
+			hint = fmt.Sprintf("%d/%d characters", length, limit)
+		}
+		return hint, l.CheckComposition(draft)
+	})
+	if err != nil {
+		return c, err
+	}
+
+	c.Body, err = term.Lines("Body (optional)", c.Body, func(body string) (string, error) {
+		draft := c
+		draft.Body = body
+		return "an empty line ends the body; lines are wrapped at 72 characters", l.CheckComposition(draft)
+	})
+	if err != nil {
+		return c, err
+	}
+
+	breaking, err := term.Confirm("Is this a breaking change?", c.Breaking != "")
+	if err != nil {
+		return c, err
+	}
+	if breaking {
+		c.Breaking, err = term.Input("Describe the breaking change", c.Breaking, func(text string) (string, error) {
+			if strings.TrimSpace(text) == "" {
+				return "", errors.New("say what breaks and how to migrate")
+			}
+			draft := c
+			draft.Breaking = text
+			return "", l.CheckComposition(draft)
+		})
+		if err != nil {
+			return c, err
+		}
+	} else {
+		c.Breaking = ""
+	}
+
+	c.Refs, err = term.Input("Issue references (optional)", c.Refs, func(refs string) (string, error) {
+		draft := c
+		draft.Refs = refs
+		return "such as PROJ-12 or #42", l.CheckComposition(draft)
+	})
+	return c, err
+}
+
+// optionIndex returns the index of the option with a value, or 0
+func optionIndex(options []ui.Option, value string) int {
+	for i, option := range options {
+		if option.Value == value {
+			return i
+		}
+	}
+	return 0
+}
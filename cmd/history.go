@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/git"
+	"github.com/randilt/git-commit-linter/internal/linter"
+	"github.com/randilt/git-commit-linter/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyRange  string
+	historyDryRun bool
+	historyEdit   bool
+
+	fixHistoryCmd = &cobra.Command{
+		Use:   "fix-history",
+		Short: "Reword the commits that fail linting",
+		Long: `Rewords the commits in --range that fail linting with a rebase that needs no
+interaction. Each message gets the fixes lint-file --fix applies; commits
+those fixes do not repair are left alone unless --edit opens them in your
+editor.
+
+Only commits that are not on any remote branch are rewritten, so published
+history is never changed. By default the range is the commits not yet on
+the upstream branch.`,
+		Example: `  git-commit-linter fix-history --dry-run
+  git-commit-linter fix-history --range=HEAD~3..HEAD --edit`,
+		Args: cobra.NoArgs,
+		RunE: fixHistory,
+	}
+
+	fixHistorySequenceEditorCmd = &cobra.Command{
+		Use:    "sequence-editor <todo>",
+		Short:  "Edit the rebase todo list for fix-history",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return git.RewordTodo(args[0])
+		},
+	}
+)
+
+func init() {
+	fixHistoryCmd.Flags().StringVar(&historyRange, "range", "", "commits to reword (default: commits not yet on the upstream branch)")
+	fixHistoryCmd.Flags().BoolVar(&historyDryRun, "dry-run", false, "show the new messages without rewriting anything")
+	fixHistoryCmd.Flags().BoolVar(&historyEdit, "edit", false, "open each failing message in the editor, with the fixes applied")
+	fixHistoryCmd.AddCommand(fixHistorySequenceEditorCmd)
+	rootCmd.AddCommand(fixHistoryCmd)
+}
+
+func fixHistory(cmd *cobra.Command, args []string) error {
+	l, repo, err := newRangeLinter()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	baseline, err := loadBaseline()
+	if err != nil {
+		return err
+	}
+	l.SetBaseline(baseline)
+
+	rewordRange := historyRange
+	if rewordRange == "" {
+		choice, err := git.DefaultRange(repo, true)
+		if err != nil {
+			return err
+		}
+		ui.Info(fmt.Sprintf("Checking %s: %s", ui.Bold(choice.Range), choice.Reason))
+		rewordRange = choice.Range
+	}
+
+	rewords, err := l.PlanRewords(cmd.Context(), rewordRange)
+	if err != nil {
+		return err
+	}
+	if len(rewords) == 0 {
+		ui.Success("No commits need rewording")
+		return nil
+	}
+
+	ui.Section("Rewording Plan")
+	messages := make(map[string]string)
+	for _, r := range rewords {
+		before, _, _ := strings.Cut(strings.TrimSpace(r.Commit.Message), "\n")
+		after, _, _ := strings.Cut(r.Message, "\n")
+		switch {
+		case r.Fixed || historyEdit:
+			messages[r.Commit.Hash] = r.Message
+			ui.Success(fmt.Sprintf("%s %s → %s", ui.Bold(r.Commit.Hash[:8]), before, after))
+		default:
+			ui.Warning(fmt.Sprintf("%s %s: %s; no safe fix, left unchanged (use --edit)",
+				ui.Bold(r.Commit.Hash[:8]), before, r.Problems[0].Message))
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	base, err := rewordBase(rewords, messages)
+	if err != nil {
+		return err
+	}
+	if historyDryRun {
+		ui.Info(fmt.Sprintf("Dry run: %d commit(s) would be reworded", len(messages)))
+		return nil
+	}
+
+	clean, err := git.IsWorkTreeClean()
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return fmt.Errorf("the work tree has uncommitted changes; commit or stash them first")
+	}
+
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := git.Reword(base, messages, historyEdit, []string{self, "fix-history", "sequence-editor"}); err != nil {
+		return err
+	}
+
+	ui.Success(fmt.Sprintf("Reworded %d commit(s)", len(messages)))
+	ui.Info(fmt.Sprintf("To undo, run %s", ui.Bold("git reset --hard "+head[:8])))
+	return nil
+}
+
+// rewordBase checks that the commits to reword can be rewritten and returns
+// the commit to rebase onto: the parent of the oldest, or an empty string
+// when that is the root commit
+func rewordBase(rewords []linter.Reword, messages map[string]string) (string, error) {
+	base := ""
+	first := true
+	for _, r := range rewords {
+		if _, ok := messages[r.Commit.Hash]; !ok {
+			continue
+		}
+
+		onBranch, err := git.IsAncestor(r.Commit.Hash, "HEAD")
+		if err != nil {
+			return "", err
+		}
+		if !onBranch {
+			return "", fmt.Errorf("commit %s is not on the checked out branch", r.Commit.Hash[:8])
+		}
+		published, err := git.IsPublished(r.Commit.Hash)
+		if err != nil {
+			return "", err
+		}
+		if published {
+			return "", fmt.Errorf("commit %s is already on a remote branch; rewording it would rewrite published history", r.Commit.Hash[:8])
+		}
+
+		if first && len(r.Commit.Parents) > 0 {
+			base = r.Commit.Parents[0]
+		}
+		first = false
+	}
+	return base, nil
+}
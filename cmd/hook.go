@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/randilt/git-commit-linter/internal/git"
+	"github.com/randilt/git-commit-linter/internal/linter"
+	"github.com/randilt/git-commit-linter/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hookCmd = &cobra.Command{
+		Use:   "hook",
+		Short: "Entry points called by installed git hooks",
+		Long: `Entry points called by the hooks install-hook writes. They take the
+arguments and standard input git passes to the hook.`,
+	}
+
+	hookPrePushCmd = &cobra.Command{
+		Use:   "pre-push <remote> <url>",
+		Short: "Lint the commits a push adds to each remote ref",
+		Args:  cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			updates, err := git.ParsePrePush(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			// New branches are checked for commits not on any remote yet
+			return lintRefUpdates(cmd.Context(), updates, git.RemoteRefs)
+		},
+	}
+
+	hookPreReceiveCmd = &cobra.Command{
+		Use:   "pre-receive",
+		Short: "Reject a push if any ref update adds bad commits",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			updates, err := git.ParsePreReceive(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			return lintRefUpdates(cmd.Context(), updates, git.AllRefs)
+		},
+	}
+
+	hookUpdateCmd = &cobra.Command{
+		Use:   "update <ref> <old> <new>",
+		Short: "Reject a ref update that adds bad commits",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			update := git.RefUpdate{Ref: args[0], Old: args[1], New: args[2]}
+			return lintRefUpdates(cmd.Context(), []git.RefUpdate{update}, git.AllRefs)
+		},
+	}
+
+	hookPrepareCommitMsgCmd = &cobra.Command{
+		Use:   "prepare-commit-msg <file> [source] [commit]",
+		Short: "Pre-fill the commit message with a conventional template",
+		Args:  cobra.RangeArgs(1, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := ""
+			if len(args) > 1 {
+				source = args[1]
+			}
+			return prepareCommitMessage(args[0], source)
+		},
+	}
+
+	hookStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show which hooks run the linter and whether they can find it",
+		Args:  cobra.NoArgs,
+		RunE:  hookStatus,
+	}
+)
+
+func init() {
+	hookCmd.AddCommand(hookPrePushCmd)
+	hookCmd.AddCommand(hookPreReceiveCmd)
+	hookCmd.AddCommand(hookUpdateCmd)
+	hookCmd.AddCommand(hookPrepareCommitMsgCmd)
+	hookCmd.AddCommand(hookStatusCmd)
+	rootCmd.AddCommand(hookCmd)
+}
+
+// lintRefUpdates lints the commits each update adds and exits with status 1
+// if any of them fail. Commits in new refs are those not reachable from the
+// refs starting with existingRefs. Everything printed reaches the pusher, prefixed
+// with "remote:" for server-side hooks.
+func lintRefUpdates(ctx context.Context, updates []git.RefUpdate, existingRefs string) error {
+	l, repo, err := newRangeLinter()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	baseline, err := loadBaseline()
+	if err != nil {
+		return err
+	}
+	l.SetBaseline(baseline)
+
+	failed := 0
+	for _, update := range updates {
+		if update.IsDelete() {
+			continue
+		}
+		if !update.IsCreate() {
+			// The remote may have commits this clone has not fetched; compare
+			// with what the remotes are known to have instead
+			if _, err := repo.ResolveRevision(update.Old); err != nil {
+				update.Old = ""
+			}
+		}
+
+		// Branch policies apply to the branch being pushed to
+		branch, _ := strings.CutPrefix(update.Ref, "refs/heads/")
+		if branch == update.Ref {
+			branch = ""
+		}
+		l.SetBranch(branch)
+		l.SetPushedTip(update.New, existingRefs)
+
+		ui.Section(fmt.Sprintf("Checking %s", update.Ref))
+		err := l.LintRevisionsContext(ctx, update.Revisions(existingRefs))
+		if _, ok := err.(*linter.ValidationError); ok {
+			failed++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return exitOnValidationError(&linter.ValidationError{Message: fmt.Sprintf("%d ref(s) failed linting", failed)})
+	}
+	return nil
+}
+
+// prepareCommitMessage puts the template in front of the message git is
+// about to open in the editor. Messages given with -m or -F, merges,
+// squashes, amends and commit.template already have content, so they are
+// left alone. A template that cannot be built never blocks the commit.
+func prepareCommitMessage(path, source string) error {
+	if source != "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cleanup := messageCleanup()
+	cleanup.CommentChar = cleanup.CommentPrefix(string(content))
+	if (linter.Cleanup{Mode: linter.CleanupStrip, CommentChar: cleanup.CommentChar}).Clean(string(content)) != "" {
+		return nil
+	}
+
+	l, repo, err := newRangeLinter()
+	if err != nil {
+		ui.Warning(fmt.Sprintf("No commit message template: %v", err))
+		return nil
+	}
+	defer repo.Close()
+	if err := l.SetCleanup(cleanup); err != nil {
+		ui.Warning(fmt.Sprintf("No commit message template: %v", err))
+		return nil
+	}
+	branch, _ := repo.CurrentBranch()
+	files, _ := git.StagedFiles()
+
+	template := l.CommitTemplate(branch, files)
+	// git's own comments follow the template
+	return os.WriteFile(path, []byte(template+"\n"+strings.TrimLeft(string(content), "\n")), 0644)
+}
+
+// hookStatus reports the installed hooks and checks that the binary they
+// call is on PATH and matches this one
+func hookStatus(cmd *cobra.Command, args []string) error {
+	ui.Section("Hooks")
+	installed := 0
+	for _, hookType := range git.HookTypes {
+		state, err := git.HookStatus(hookType)
+		if err != nil {
+			return err
+		}
+		switch {
+		case state.Installed && state.Chained:
+			installed++
+			ui.Success(fmt.Sprintf("%s: installed alongside another hook (%s)", hookType, state.Path))
+		case state.Installed:
+			installed++
+			ui.Success(fmt.Sprintf("%s: installed (%s)", hookType, state.Path))
+		case state.Exists:
+			ui.Info(fmt.Sprintf("%s: not installed; another hook is in place (%s)", hookType, state.Path))
+		default:
+			ui.Info(fmt.Sprintf("%s: not installed", hookType))
+		}
+	}
+
+	ui.Section("Binary")
+	path, err := exec.LookPath("git-commit-linter")
+	if err != nil {
+		if installed > 0 {
+			ui.Error("git-commit-linter is not on PATH; the installed hooks will fail")
+		} else {
+			ui.Warning("git-commit-linter is not on PATH")
+		}
+		return nil
+	}
+
+	found, err := binaryVersion(cmd.Context(), path)
+	switch {
+	case err != nil:
+		ui.Warning(fmt.Sprintf("%s: could not read its version: %v", path, err))
+	case found != version:
+		ui.Warning(fmt.Sprintf("%s is version %s, but this is version %s", path, found, version))
+	default:
+		ui.Success(fmt.Sprintf("%s (version %s)", path, found))
+	}
+	return nil
+}
+
+// binaryVersion runs "<path> version" and returns the version it prints
+func binaryVersion(ctx context.Context, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "version").Output()
+	if err != nil {
+		return "", err
+	}
+	found, ok := strings.CutPrefix(strings.TrimSpace(string(out)), "git-commit-linter version ")
+	if !ok {
+		return "", fmt.Errorf("unexpected output %q", strings.TrimSpace(string(out)))
+	}
+	return found, nil
+}
@@ -1,21 +1,49 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/randilt/git-commit-linter/internal/config"
 	"github.com/randilt/git-commit-linter/internal/git"
 	"github.com/randilt/git-commit-linter/internal/linter"
+	"github.com/randilt/git-commit-linter/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 var (
-	version     string
-	commit      string
-	date        string
-	configPath  string
-	commitRange string
+	version       string
+	commit        string
+	date          string
+	configPath    string
+	commitRange   string
+	gitBackend    string
+	jobs          int
+	noCache       bool
+	baselinePath  string
+	sinceUpstream bool
+	hookType      string
+	hookChain     string
+	hookForce     bool
+	hookYes       bool
+	hookAll       bool
+	hookManager   string
+	hookFix       bool
+	cleanupMode   string
+	fixMessage    bool
+
+	filterAuthors        []string
+	filterExcludeAuthors []string
+	filterCommitters     []string
+	filterSince          string
+	filterUntil          string
+	filterPaths          []string
+	filterGrep           []string
 
 	rootCmd = &cobra.Command{
 		Use:   "git-commit-linter",
@@ -27,12 +55,32 @@ Example: git-commit-linter --config=config.yaml --check="HEAD~5..HEAD"`,
 
 	installHookCmd = &cobra.Command{
 		Use:   "install-hook",
-		Short: "Install git commit-msg hook",
-		Long: `Installs a git commit-msg hook that will automatically lint 
-commit messages before they are committed.`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return git.InstallHook()
-		},
+		Short: "Install a git hook that runs the linter",
+		Long: `Installs a git hook that automatically lints commit messages. By default
+this is a commit-msg hook that checks each message before it is committed.
+
+Use --type=pre-push to check the commits being pushed instead, which cannot
+be skipped by committing with --no-verify. On a self-hosted git server, use
+--type=pre-receive or --type=update in the bare repository to reject pushes
+containing bad commits.
+
+An existing hook from another tool is kept: the linter runs before or after
+it (--chain), inside a marked block that reinstalling updates in place.
+--force replaces it instead, and --yes chains before it without asking.
+
+In a repository set up for pre-commit, lefthook or husky, the commit-msg
+hook is added to that manager's config instead; --manager picks one, or
+--manager=none writes to the hooks directory regardless.`,
+		RunE: installHook,
+	}
+
+	uninstallHookCmd = &cobra.Command{
+		Use:   "uninstall-hook",
+		Short: "Remove the linter from a git hook",
+		Long: `Removes what install-hook wrote. Only the linter's marked block is removed
+from a hook shared with another tool; a hook that only ran the linter is
+deleted, and the hook install-hook --force set aside is restored.`,
+		RunE: uninstallHook,
 	}
 
 	versionCmd = &cobra.Command{
@@ -45,11 +93,56 @@ commit messages before they are committed.`,
 		},
 	}
 
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage cached lint results",
+	}
+
+	cacheClearCmd = &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all cached lint results",
+		RunE:  clearCache,
+	}
+
+	baselineCmd = &cobra.Command{
+		Use:   "baseline",
+		Short: "Manage the baseline of known violations",
+	}
+
+	baselineCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Record the violations in a commit range as known",
+		Long: `Lints the commits in --check, or the whole history by default, and writes
+every violation found to the baseline file. Later runs do not report those
+violations, so only new problems fail. Check the file in to share it.`,
+		Example: `  git-commit-linter baseline create --check=v1.0`,
+		RunE:    createBaseline,
+	}
+
 	lintFileCmd = &cobra.Command{
 		Use:   "lint-file [file]",
 		Short: "Lint a commit message from a file",
-		Args:  cobra.ExactArgs(1),
-		RunE:  lintFile,
+		Long: `Lints the commit message in a file, such as the one git passes to the
+commit-msg hook. Use "-" to read the message from standard input.
+
+With --fix, mistakes that have only one reading are corrected in the file
+first: the case of the type, spacing in the header, a trailing period, a
+missing or unknown type the message's keywords point to, a missing required
+scope path_scopes points to, and body lines longer than 72 characters. What
+changed is shown before the result is linted.`,
+		Example: `  gh pr view 42 --json body --jq .body | git-commit-linter lint-file -`,
+		Args:    cobra.ExactArgs(1),
+		RunE:    lintFile,
+	}
+
+	lintCmd = &cobra.Command{
+		Use:   "lint <message>",
+		Short: "Lint a commit message given on the command line",
+		Long: `Lints a literal commit message, such as a pull request title or the
+message a squash merge will use.`,
+		Example: `  git-commit-linter lint "feat(auth): add login with SSO"`,
+		Args:    cobra.ExactArgs(1),
+		RunE:    lintMessage,
 	}
 )
 
@@ -60,25 +153,284 @@ func SetVersion(v, c, d string) {
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	// Stop reading the log on Ctrl-C instead of leaving git log behind
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "path to config file")
-	rootCmd.PersistentFlags().StringVar(&commitRange, "check", "HEAD^..HEAD", "commit range to check")
+	rootCmd.PersistentFlags().StringVar(&commitRange, "check", "", "commit range to check (default: commits not yet on the upstream branch)")
+	rootCmd.PersistentFlags().StringVar(&gitBackend, "git-backend", git.BackendExec, "how to read commits: exec (git binary) or native (read .git directly)")
+	rootCmd.Flags().BoolVar(&sinceUpstream, "since-upstream", false, "check commits not yet on the upstream branch, failing if there is none")
+	rootCmd.Flags().IntVar(&jobs, "jobs", 0, "number of commits to lint in parallel (default: number of CPUs)")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "lint every commit instead of reusing cached results")
+	rootCmd.PersistentFlags().StringVar(&baselinePath, "baseline", "", "baseline of known violations (default \""+linter.DefaultBaselinePath+"\" if it exists)")
+	rootCmd.Flags().StringArrayVar(&filterAuthors, "author", nil, "only lint commits whose author matches this regex (repeatable)")
+	rootCmd.Flags().StringArrayVar(&filterExcludeAuthors, "exclude-author", nil, "skip commits whose author matches this regex (repeatable)")
+	rootCmd.Flags().StringArrayVar(&filterCommitters, "committer", nil, "only lint commits whose committer matches this regex (repeatable)")
+	rootCmd.Flags().StringVar(&filterSince, "since", "", "only lint commits committed after this date")
+	rootCmd.Flags().StringVar(&filterUntil, "until", "", "only lint commits committed before this date")
+	rootCmd.Flags().StringArrayVar(&filterPaths, "path", nil, "only lint commits touching this path or glob (repeatable)")
+	rootCmd.Flags().StringArrayVar(&filterGrep, "grep", nil, "only lint commits whose message matches this regex (repeatable)")
+	lintFileCmd.Flags().StringVar(&cleanupMode, "cleanup", "", "how to clean up the message before linting, as in git commit --cleanup: "+strings.Join(linter.CleanupModes, ", ")+" (default: commit.cleanup)")
+	lintFileCmd.Flags().BoolVar(&fixMessage, "fix", false, "apply safe fixes to the message file before linting it")
+	installHookCmd.Flags().StringVar(&hookType, "type", git.HookCommitMsg, "hook to install: "+strings.Join(git.HookTypes, ", "))
+	installHookCmd.Flags().StringVar(&hookChain, "chain", "", "keep an existing hook and run the linter before or after it")
+	installHookCmd.Flags().BoolVar(&hookForce, "force", false, "overwrite an existing hook, saving it as <hook>.backup")
+	installHookCmd.Flags().BoolVarP(&hookYes, "yes", "y", false, "do not prompt; run the linter before an existing hook")
+	installHookCmd.Flags().BoolVar(&hookFix, "fix", false, "make the commit-msg hook apply safe fixes to the message (see lint-file --fix)")
+	installHookCmd.Flags().StringVar(&hookManager, "manager", "", "add the linter to the config of a hook manager ("+strings.Join(git.HookManagers, ", ")+"), or \"none\" for the hooks directory; detected by default")
+	installHookCmd.MarkFlagsMutuallyExclusive("chain", "force")
+	uninstallHookCmd.Flags().StringVar(&hookType, "type", git.HookCommitMsg, "hook to uninstall: "+strings.Join(git.HookTypes, ", "))
+	uninstallHookCmd.Flags().BoolVar(&hookAll, "all", false, "uninstall every hook type")
+	uninstallHookCmd.MarkFlagsMutuallyExclusive("type", "all")
+	cacheCmd.AddCommand(cacheClearCmd)
+	baselineCmd.AddCommand(baselineCreateCmd)
+	rootCmd.AddCommand(baselineCmd)
+	rootCmd.AddCommand(cacheCmd)
 	rootCmd.AddCommand(installHookCmd)
+	rootCmd.AddCommand(uninstallHookCmd)
 	rootCmd.AddCommand(lintFileCmd)
+	rootCmd.AddCommand(lintCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
 func runLinter(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load(configPath)
+	if commitRange != "" && sinceUpstream {
+		return fmt.Errorf("--check and --since-upstream cannot be used together")
+	}
+
+	l, repo, err := newRangeLinter()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	baseline, err := loadBaseline()
 	if err != nil {
 		return err
 	}
+	l.SetBaseline(baseline)
+
+	filter, err := commitFilter()
+	if err != nil {
+		return err
+	}
+	if err := l.SetFilter(filter); err != nil {
+		return err
+	}
+
+	checkRange := commitRange
+	if checkRange == "" {
+		choice, err := git.DefaultRange(repo, sinceUpstream)
+		if err != nil {
+			return err
+		}
+		ui.Info(fmt.Sprintf("Checking %s: %s", ui.Bold(choice.Range), choice.Reason))
+		checkRange = choice.Range
+	}
+	return exitOnValidationError(l.LintCommitsContext(cmd.Context(), checkRange))
+}
+
+// exitOnValidationError exits with status 1 when linting found problems,
+// which have already been printed, and returns any other error to cobra
+func exitOnValidationError(err error) error {
+	if _, ok := err.(*linter.ValidationError); ok {
+		os.Exit(1)
+	}
+	return err
+}
+
+// commitFilter builds the filter from the command line flags
+func commitFilter() (linter.Filter, error) {
+	filter := linter.Filter{
+		Authors:        filterAuthors,
+		ExcludeAuthors: filterExcludeAuthors,
+		Committers:     filterCommitters,
+		Paths:          filterPaths,
+		Grep:           filterGrep,
+	}
+
+	now := time.Now()
+	var err error
+	if filterSince != "" {
+		if filter.Since, err = linter.ParseDate(filterSince, now); err != nil {
+			return filter, fmt.Errorf("--since: %w", err)
+		}
+	}
+	if filterUntil != "" {
+		if filter.Until, err = linter.ParseDate(filterUntil, now); err != nil {
+			return filter, fmt.Errorf("--until: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// newRangeLinter sets up a linter for checking commits in a repository.
+// The caller closes the repository.
+func newRangeLinter() (*linter.Linter, git.Repository, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repo, err := git.Open(gitBackend)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	l := linter.New(cfg)
-	return l.LintCommits(commitRange)
+	l.SetRepository(repo)
+	l.SetWorkers(jobs)
+	if !noCache {
+		// Without a git directory there is nowhere to cache, so just lint
+		if dir, err := repo.CommonDir(); err == nil {
+			l.SetCacheDir(linter.CacheDir(dir))
+		}
+	}
+	return l, repo, nil
+}
+
+// loadBaseline reads the --baseline file. The default file is optional.
+func loadBaseline() (*linter.Baseline, error) {
+	path := baselinePath
+	if path == "" {
+		path = linter.DefaultBaselinePath
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+
+	baseline, err := linter.LoadBaseline(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load baseline: %w", err)
+	}
+	return baseline, nil
+}
+
+func createBaseline(cmd *cobra.Command, args []string) error {
+	l, repo, err := newRangeLinter()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	checkRange := commitRange
+	if checkRange == "" {
+		checkRange = "HEAD"
+	}
+	baseline, err := l.CreateBaseline(cmd.Context(), checkRange)
+	if err != nil {
+		return err
+	}
+
+	path := baselinePath
+	if path == "" {
+		path = linter.DefaultBaselinePath
+	}
+	if err := baseline.Save(path); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	ui.Success(fmt.Sprintf("Recorded %d violation(s) in %d commit(s) to %s",
+		baseline.Len(), len(baseline.Commits), ui.Bold(path)))
+	return nil
+}
+
+func installHook(cmd *cobra.Command, args []string) error {
+	hookConfig := configPath
+	if hookConfig != "" {
+		// Hooks run from the top of the work tree, or the bare repository
+		abs, err := filepath.Abs(hookConfig)
+		if err != nil {
+			return err
+		}
+		hookConfig = abs
+	}
+	opts := git.HookOptions{
+		ConfigPath: hookConfig,
+		Chain:      hookChain,
+		Force:      hookForce,
+		Yes:        hookYes,
+		Fix:        hookFix,
+	}
+	if hookFix && hookType != git.HookCommitMsg {
+		return fmt.Errorf("--fix only applies to the %s hook", git.HookCommitMsg)
+	}
+
+	manager := hookManager
+	if manager == "" {
+		var err error
+		if manager, err = detectHookManager(); err != nil {
+			return err
+		}
+	}
+	if manager == "none" {
+		return git.InstallHook(hookType, opts)
+	}
+	return git.InstallManagedHook(manager, hookType, opts)
+}
+
+// detectHookManager returns the hook manager the repository is set up for,
+// or "none" to install into the hooks directory
+func detectHookManager() (string, error) {
+	root, err := git.TopLevel()
+	if err != nil {
+		// A bare repository has no manager config
+		return "none", nil
+	}
+
+	managers := git.DetectHookManagers(root)
+	switch {
+	case len(managers) == 0:
+		return "none", nil
+	case len(managers) > 1:
+		return "", fmt.Errorf("found configs for several hook managers (%s); choose one with --manager", strings.Join(managers, ", "))
+	case hookType != git.HookCommitMsg:
+		ui.Warning(fmt.Sprintf("This repository uses %s, but only the %s hook can be added to it; writing the %s hook to the hooks directory",
+			managers[0], git.HookCommitMsg, hookType))
+		return "none", nil
+	}
+	ui.Info(fmt.Sprintf("Found a %s config; use --manager=none to write to the hooks directory instead", managers[0]))
+	return managers[0], nil
+}
+
+func uninstallHook(cmd *cobra.Command, args []string) error {
+	types := []string{hookType}
+	if hookAll {
+		types = git.HookTypes
+	}
+
+	for _, t := range types {
+		removed, err := git.UninstallHook(t)
+		if err != nil {
+			return err
+		}
+		if removed {
+			ui.Success(fmt.Sprintf("Removed the linter from the %s hook", t))
+		} else if !hookAll {
+			ui.Info(fmt.Sprintf("The linter is not installed as a %s hook", t))
+		}
+	}
+	return nil
+}
+
+func clearCache(cmd *cobra.Command, args []string) error {
+	repo, err := git.Open(gitBackend)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	dir, err := repo.CommonDir()
+	if err != nil {
+		return fmt.Errorf("failed to find git directory: %w", err)
+	}
+	if err := linter.ClearCache(linter.CacheDir(dir)); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	ui.Success("Cache cleared")
+	return nil
 }
 
 func lintFile(cmd *cobra.Command, args []string) error {
@@ -88,13 +440,54 @@ func lintFile(cmd *cobra.Command, args []string) error {
 	}
 
 	l := linter.New(cfg)
-	if err := l.LintCommitMessageFile(args[0]); err != nil {
-		if _, ok := err.(*linter.ValidationError); ok {
-			// For validation errors, just exit with status code 1
-			os.Exit(1)
-		}
-		// For other errors, return them to cobra
+	if err := l.SetCleanup(messageCleanup()); err != nil {
 		return err
 	}
-	return nil
+	if args[0] == "-" {
+		if fixMessage {
+			return fmt.Errorf("--fix rewrites the message file and cannot be used with standard input")
+		}
+		return exitOnValidationError(l.LintCommitMessageReader(cmd.InOrStdin()))
+	}
+	if fixMessage {
+		return exitOnValidationError(l.FixCommitMessageFile(args[0]))
+	}
+	return exitOnValidationError(l.LintCommitMessageFile(args[0]))
+}
+
+// messageCleanup returns how message files are cleaned up, following
+// --cleanup, commit.cleanup and core.commentChar. Without git, messages
+// are stripped of "#" comments.
+func messageCleanup() linter.Cleanup {
+	mode := cleanupMode
+	if mode == "" {
+		mode, _ = git.ConfigValue("commit.cleanup")
+	}
+	if mode == "" || mode == linter.CleanupDefault {
+		// git runs the commit-msg hook with GIT_EDITOR=: when it did not
+		// open an editor, and only strips comments from edited messages
+		mode = linter.CleanupStrip
+		if os.Getenv("GIT_EDITOR") == ":" {
+			mode = linter.CleanupWhitespace
+		}
+	}
+
+	comment, _ := git.ConfigValue("core.commentString")
+	if comment == "" {
+		comment, _ = git.ConfigValue("core.commentChar")
+	}
+	return linter.Cleanup{Mode: mode, CommentChar: comment}
+}
+
+func lintMessage(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	message := strings.TrimSpace(args[0])
+	if message == "" {
+		return fmt.Errorf("the commit message is empty")
+	}
+	return exitOnValidationError(linter.New(cfg).LintCommitMessage(message))
 }
@@ -1,11 +1,31 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path"
+	"regexp"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
+// Severity levels a rule can report with
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Parts of a commit message a custom rule can target
+const (
+	TargetHeader  = "header"
+	TargetSubject = "subject"
+	TargetScope   = "scope"
+	TargetBody    = "body"
+	TargetFooter  = "footer"
+	TargetMessage = "message"
+)
+
 type Config struct {
 	Types  []string `yaml:"types"`
 	Scopes []string `yaml:"scopes,omitempty"`
@@ -13,6 +33,175 @@ type Config struct {
 		RequireScope     bool `yaml:"require_scope"`
 		MaxMessageLength int  `yaml:"max_message_length"`
 	} `yaml:"rules"`
+	CustomRules []CustomRule  `yaml:"custom_rules,omitempty"`
+	Secrets     SecretsConfig `yaml:"secrets,omitempty"`
+	Header      HeaderConfig  `yaml:"header,omitempty"`
+	ScopeSyntax ScopeSyntax   `yaml:"scope_syntax,omitempty"`
+
+	SpecialCommits SpecialCommits `yaml:"special_commits,omitempty"`
+	PathScopes     PathScopes     `yaml:"path_scopes,omitempty"`
+	Ignore         IgnoreConfig   `yaml:"ignore,omitempty"`
+}
+
+// IgnoreConfig lists commits that are never linted, such as those made by
+// bots. Authors match the author's name or email, case-insensitively, and
+// may use "*" as a wildcard; no other character is special, so bot names
+// like "dependabot[bot]" can be listed as they are. Messages are regular
+// expressions matched against the whole commit message.
+//
+// Example:
+//
+//	ignore:
+//	  authors: ["dependabot[bot]", "renovate[bot]", "*@bots.example.com"]
+//	  messages: ["^Bump \\S+ from"]
+type IgnoreConfig struct {
+	Authors  []string `yaml:"authors,omitempty"`
+	Messages []string `yaml:"messages,omitempty"`
+
+	authors  []*regexp.Regexp
+	messages []*regexp.Regexp
+}
+
+// PathScopes maps file paths to the scopes they belong to, so a commit's
+// scope can be checked against the files it touches. Patterns are globs
+// where "**" matches any number of directories.
+//
+// Example:
+//
+//	path_scopes:
+//	  severity: warning
+//	  mappings:
+//	    - scope: auth
+//	      paths: ["services/auth/**"]
+//	    - scope: ui
+//	      paths: ["web/**", "**/*.css"]
+type PathScopes struct {
+	Severity string      `yaml:"severity,omitempty"`
+	Mappings []PathScope `yaml:"mappings,omitempty"`
+}
+
+// PathScope assigns a scope to the files matching any of its paths
+type PathScope struct {
+	Scope string   `yaml:"scope"`
+	Paths []string `yaml:"paths"`
+}
+
+// Policies for git-generated commit messages
+const (
+	PolicyIgnore = "ignore"
+	PolicyLint   = "lint"
+	PolicyFail   = "fail"
+)
+
+// SpecialCommitPolicy decides how each category of git-generated message is
+// handled: "ignore" skips it, "lint" checks the message it refers to (the
+// reverted or fixed-up header) and "fail" rejects it outright
+type SpecialCommitPolicy struct {
+	Merge      string `yaml:"merge,omitempty"`
+	Revert     string `yaml:"revert,omitempty"`
+	Autosquash string `yaml:"autosquash,omitempty"`
+}
+
+// SpecialCommits configures merge, revert and fixup!/squash!/amend! commits.
+// Branch entries override the default policy when the current branch
+// matches their name, which may be a glob such as "release/*".
+//
+// Example:
+//
+//	special_commits:
+//	  merge: ignore
+//	  revert: lint
+//	  autosquash: lint
+//	  branches:
+//	    - name: main
+//	      autosquash: fail
+type SpecialCommits struct {
+	SpecialCommitPolicy `yaml:",inline"`
+	Branches            []BranchPolicy `yaml:"branches,omitempty"`
+}
+
+// BranchPolicy overrides the special commit policy on matching branches
+type BranchPolicy struct {
+	Name                string `yaml:"name"`
+	SpecialCommitPolicy `yaml:",inline"`
+}
+
+// ScopeSyntax controls how the scope part of a header is split into
+// multiple and hierarchical scopes. The zero value allows a single flat
+// scope, as in "feat(api): ...".
+//
+// Example:
+//
+//	scope_syntax:
+//	  delimiters: [","]                # feat(api,db): ...
+//	  max_scopes: 2
+//	  hierarchy_separators: ["/", "."] # fix(ui/button): ...
+type ScopeSyntax struct {
+	Delimiters          []string `yaml:"delimiters,omitempty"`
+	MaxScopes           int      `yaml:"max_scopes,omitempty"`
+	HierarchySeparators []string `yaml:"hierarchy_separators,omitempty"`
+}
+
+// Emoji prefix modes for HeaderConfig.Emoji
+const (
+	EmojiForbidden = "forbidden"
+	EmojiOptional  = "optional"
+	EmojiRequired  = "required"
+)
+
+// HeaderConfig controls optional parts of the header grammar
+//
+// Example:
+//
+//	header:
+//	  emoji: optional          # forbidden (default), optional or required
+//	  emoji_matches_type: true # ":bug: feat: ..." is rejected
+type HeaderConfig struct {
+	Emoji            string `yaml:"emoji,omitempty"`
+	EmojiMatchesType bool   `yaml:"emoji_matches_type,omitempty"`
+}
+
+// SecretsConfig controls detection of credentials and forbidden words in
+// commit messages. Detection is enabled unless Disabled is set. Strings
+// that only look random are reported with EntropySeverity, a warning
+// unless set.
+//
+// Example:
+//
+//	secrets:
+//	  deny_list: ["internal.example.com", "hunter2"]
+//	  entropy_threshold: 4.5
+//	  entropy_severity: error
+type SecretsConfig struct {
+	Disabled         bool     `yaml:"disabled,omitempty"`
+	DenyList         []string `yaml:"deny_list,omitempty"`
+	EntropyThreshold float64  `yaml:"entropy_threshold,omitempty"`
+	MinTokenLength   int      `yaml:"min_token_length,omitempty"`
+	EntropySeverity  string   `yaml:"entropy_severity,omitempty"`
+
+	denyList []*regexp.Regexp
+}
+
+// CustomRule is a team-specific regex check defined in the config file
+//
+// Example:
+//
+//	custom_rules:
+//	  - name: no-wip
+//	    target: subject
+//	    must_not_match: "(?i)\\bwip\\b"
+//	    severity: error
+//	    message: "work-in-progress commits must not be pushed"
+type CustomRule struct {
+	Name         string `yaml:"name"`
+	Target       string `yaml:"target"`
+	MustMatch    string `yaml:"must_match,omitempty"`
+	MustNotMatch string `yaml:"must_not_match,omitempty"`
+	Severity     string `yaml:"severity,omitempty"`
+	Message      string `yaml:"message,omitempty"`
+
+	mustMatch    *regexp.Regexp
+	mustNotMatch *regexp.Regexp
 }
 
 func Load(path string) (*Config, error) {
@@ -40,5 +229,255 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := cfg.Compile(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
+
+// Compile validates the config and compiles the regular expressions used by
+// custom rules and the secrets deny list. Load calls it automatically;
+// configs built in code must call it before being handed to the linter.
+func (c *Config) Compile() error {
+	for i := range c.CustomRules {
+		if err := c.CustomRules[i].compile(); err != nil {
+			return err
+		}
+	}
+	if err := c.Secrets.compile(); err != nil {
+		return err
+	}
+	if err := c.Header.compile(); err != nil {
+		return err
+	}
+	if err := c.ScopeSyntax.compile(); err != nil {
+		return err
+	}
+	if err := c.SpecialCommits.compile(); err != nil {
+		return err
+	}
+	if err := c.PathScopes.compile(); err != nil {
+		return err
+	}
+	return c.Ignore.compile()
+}
+
+func (i *IgnoreConfig) compile() error {
+	i.authors = nil
+	for _, author := range i.Authors {
+		if strings.TrimSpace(author) == "" {
+			return fmt.Errorf("ignore: authors contains an empty entry")
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(author), `\*`, ".*")
+		i.authors = append(i.authors, regexp.MustCompile("(?i)^"+pattern+"$"))
+	}
+
+	i.messages = nil
+	for _, message := range i.Messages {
+		re, err := regexp.Compile(message)
+		if err != nil {
+			return fmt.Errorf("ignore: invalid message pattern %q: %w", message, err)
+		}
+		i.messages = append(i.messages, re)
+	}
+	return nil
+}
+
+// IgnoresAuthor reports whether an author's name or email is listed
+func (i *IgnoreConfig) IgnoresAuthor(name, email string) bool {
+	for _, re := range i.authors {
+		if re.MatchString(name) || re.MatchString(email) {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoresMessage reports whether a commit message matches a listed pattern
+func (i *IgnoreConfig) IgnoresMessage(message string) bool {
+	for _, re := range i.messages {
+		if re.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PathScopes) compile() error {
+	switch p.Severity {
+	case SeverityError, SeverityWarning:
+	case "":
+		p.Severity = SeverityWarning
+	default:
+		return fmt.Errorf("path_scopes: unknown severity %q", p.Severity)
+	}
+
+	for _, m := range p.Mappings {
+		if m.Scope == "" || len(m.Paths) == 0 {
+			return fmt.Errorf("path_scopes: every mapping needs a scope and at least one path")
+		}
+		for _, pattern := range m.Paths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("path_scopes: invalid pattern %q for scope %s: %w", pattern, m.Scope, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SpecialCommits) compile() error {
+	if err := s.SpecialCommitPolicy.validate("special_commits"); err != nil {
+		return err
+	}
+	for _, b := range s.Branches {
+		if b.Name == "" {
+			return fmt.Errorf("special_commits: branch entry is missing a name")
+		}
+		if _, err := path.Match(b.Name, ""); err != nil {
+			return fmt.Errorf("special_commits: invalid branch pattern %q: %w", b.Name, err)
+		}
+		if err := b.SpecialCommitPolicy.validate("special_commits: branch " + b.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p SpecialCommitPolicy) validate(context string) error {
+	for _, policy := range []string{p.Merge, p.Revert, p.Autosquash} {
+		switch policy {
+		case "", PolicyIgnore, PolicyLint, PolicyFail:
+		default:
+			return fmt.Errorf("%s: unknown policy %q", context, policy)
+		}
+	}
+	return nil
+}
+
+func (s *ScopeSyntax) compile() error {
+	for _, sep := range append(append([]string{}, s.Delimiters...), s.HierarchySeparators...) {
+		if sep == "" || strings.ContainsAny(sep, "()") {
+			return fmt.Errorf("scope_syntax: invalid separator %q", sep)
+		}
+	}
+	for _, d := range s.Delimiters {
+		for _, h := range s.HierarchySeparators {
+			if d == h {
+				return fmt.Errorf("scope_syntax: %q cannot be both a delimiter and a hierarchy separator", d)
+			}
+		}
+	}
+	if s.MaxScopes < 0 {
+		return fmt.Errorf("scope_syntax: max_scopes must not be negative")
+	}
+	return nil
+}
+
+func (h *HeaderConfig) compile() error {
+	switch h.Emoji {
+	case EmojiForbidden, EmojiOptional, EmojiRequired:
+	case "":
+		h.Emoji = EmojiForbidden
+	default:
+		return fmt.Errorf("header: unknown emoji mode %q", h.Emoji)
+	}
+	return nil
+}
+
+func (s *SecretsConfig) compile() error {
+	s.denyList = nil
+	for _, word := range s.DenyList {
+		if strings.TrimSpace(word) == "" {
+			return fmt.Errorf("secrets: deny_list contains an empty entry")
+		}
+		pattern := regexp.QuoteMeta(word)
+		// Only anchor on word boundaries where the entry itself starts or
+		// ends with a word character, so entries like "-----" still match
+		if isWordByte(word[0]) {
+			pattern = `\b` + pattern
+		}
+		if isWordByte(word[len(word)-1]) {
+			pattern += `\b`
+		}
+		s.denyList = append(s.denyList, regexp.MustCompile("(?i)"+pattern))
+	}
+	if s.EntropyThreshold < 0 || s.MinTokenLength < 0 {
+		return fmt.Errorf("secrets: entropy_threshold and min_token_length must not be negative")
+	}
+	switch s.EntropySeverity {
+	case SeverityError, SeverityWarning:
+	case "":
+		s.EntropySeverity = SeverityWarning
+	default:
+		return fmt.Errorf("secrets: unknown entropy_severity %q", s.EntropySeverity)
+	}
+	return nil
+}
+
+// DeniedWords returns the deny_list entries found in text
+func (s *SecretsConfig) DeniedWords(text string) []string {
+	var found []string
+	for i, re := range s.denyList {
+		if re.MatchString(text) {
+			found = append(found, s.DenyList[i])
+		}
+	}
+	return found
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+func (r *CustomRule) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("custom rule is missing a name")
+	}
+
+	switch r.Target {
+	case TargetHeader, TargetSubject, TargetScope, TargetBody, TargetFooter, TargetMessage:
+	case "":
+		r.Target = TargetHeader
+	default:
+		return fmt.Errorf("custom rule %q: unknown target %q", r.Name, r.Target)
+	}
+
+	switch r.Severity {
+	case SeverityError, SeverityWarning:
+	case "":
+		r.Severity = SeverityError
+	default:
+		return fmt.Errorf("custom rule %q: unknown severity %q", r.Name, r.Severity)
+	}
+
+	if r.MustMatch == "" && r.MustNotMatch == "" {
+		return fmt.Errorf("custom rule %q: one of must_match or must_not_match is required", r.Name)
+	}
+
+	var err error
+	if r.MustMatch != "" {
+		if r.mustMatch, err = regexp.Compile(r.MustMatch); err != nil {
+			return fmt.Errorf("custom rule %q: invalid must_match: %w", r.Name, err)
+		}
+	}
+	if r.MustNotMatch != "" {
+		if r.mustNotMatch, err = regexp.Compile(r.MustNotMatch); err != nil {
+			return fmt.Errorf("custom rule %q: invalid must_not_match: %w", r.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Passes reports whether text satisfies the rule's must_match and
+// must_not_match patterns
+func (r *CustomRule) Passes(text string) bool {
+	if r.mustMatch != nil && !r.mustMatch.MatchString(text) {
+		return false
+	}
+	if r.mustNotMatch != nil && r.mustNotMatch.MatchString(text) {
+		return false
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -53,3 +54,117 @@ rules:
 		t.Errorf("Expected MaxMessageLength = 50, got %d", cfg.Rules.MaxMessageLength)
 	}
 }
+
+func TestLoadCustomRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{
+			name: "valid rule",
+			config: `
+custom_rules:
+  - name: no-wip
+    target: subject
+    must_not_match: "(?i)wip"
+`,
+			wantErr: false,
+		},
+		{
+			name: "invalid regex",
+			config: `
+custom_rules:
+  - name: broken
+    must_match: "([a-z"
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown target",
+			config: `
+custom_rules:
+  - name: bad-target
+    target: trailer
+    must_match: "x"
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing pattern",
+			config: `
+custom_rules:
+  - name: empty
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempConfig(t, tt.config)
+			cfg, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			rule := cfg.CustomRules[0]
+			if rule.Severity != SeverityError {
+				t.Errorf("Expected default severity %q, got %q", SeverityError, rule.Severity)
+			}
+			if rule.Passes("feat: WIP thing") {
+				t.Error("Rule should reject a WIP subject")
+			}
+		})
+	}
+}
+
+func TestLoadIgnore(t *testing.T) {
+	path := writeTempConfig(t, `
+ignore:
+  authors: ["dependabot[bot]", "*@bots.example.com"]
+  messages: ["^Bump \\S+ from"]
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	authors := []struct {
+		name, email string
+		want        bool
+	}{
+		{"dependabot[bot]", "49699333+dependabot[bot]@users.noreply.github.com", true},
+		{"Dependabot[Bot]", "", true},
+		{"dependabotb", "", false},
+		{"Release Bot", "release@bots.example.com", true},
+		{"Ada Lovelace", "ada@example.com", false},
+	}
+	for _, a := range authors {
+		if got := cfg.Ignore.IgnoresAuthor(a.name, a.email); got != a.want {
+			t.Errorf("IgnoresAuthor(%q, %q) = %v, want %v", a.name, a.email, got, a.want)
+		}
+	}
+
+	if !cfg.Ignore.IgnoresMessage("Bump golang.org/x/sys from 0.24.0 to 0.25.0") {
+		t.Error("IgnoresMessage() should match a dependabot message")
+	}
+	if cfg.Ignore.IgnoresMessage("feat: bump the version") {
+		t.Error("IgnoresMessage() should not match an ordinary message")
+	}
+
+	if _, err := Load(writeTempConfig(t, "ignore:\n  messages: [\"([\"]\n")); err == nil {
+		t.Error("Load() should reject an invalid ignore pattern")
+	}
+}
+
+func writeTempConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
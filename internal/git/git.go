@@ -1,15 +1,47 @@
 package git
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Commit struct {
-	Hash    string
+	Hash string
+	// Parents is nil when unknown, as for a message that is not committed yet
+	Parents   []string
+	Author    Signature
+	Committer Signature
+	// Subject and Body are split from the raw message the way git does:
+	// the subject is the first paragraph joined into one line
+	Subject string
+	Body    string
 	Message string
 }
 
+// Signature identifies the author or committer of a commit
+type Signature struct {
+	Name  string
+	Email string
+	Date  time.Time
+}
+
+// logFormat prints one record per commit. Records end with NUL, which git
+// never emits inside a commit message, and fields are separated by the
+// ASCII record separator. The raw message comes last, so separators inside
+// it cannot shift the other fields.
+const logFormat = "%H%x1e%P%x1e%an%x1e%ae%x1e%aI%x1e%cn%x1e%ce%x1e%cI%x1e%B%x00"
+
+const logFields = 9
+
 // GetCommits returns a list of commits from a commit range
 //
 // The function accepts a Git commit range (e.g., "HEAD~5..HEAD") and returns a list of commits
@@ -26,30 +58,300 @@ type Commit struct {
 //
 // Returns a list of commits or an error if the command fails
 func GetCommits(commitRange string) ([]Commit, error) {
-	cmd := exec.Command("git", "log", "--format=%H%n%B%n---", commitRange)
-	output, err := cmd.Output()
+	commits := []Commit{}
+	err := StreamCommits(context.Background(), commitRange, func(c Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return commits, nil
+}
 
+// StreamCommits runs git log over a commit range and calls fn for each
+// commit as soon as it has been read, newest first. Unlike GetCommits it
+// never holds the whole log in memory. It stops early when fn returns an
+// error or ctx is cancelled. The range is a single revision argument, such
+// as "HEAD~5..HEAD", and is never read as an option.
+func StreamCommits(ctx context.Context, commitRange string, fn func(Commit) error) error {
+	args := []string{"--end-of-options"}
+	if commitRange != "" {
+		args = append(args, commitRange)
+	}
+	return streamLog(ctx, args, fn)
+}
+
+// StreamRevisions is StreamCommits for the commits selected by revs
+func StreamRevisions(ctx context.Context, revs Revisions, fn func(Commit) error) error {
+	var args []string
+	if len(revs.ExcludeRefs) > 0 {
+		args = append(args, "--not")
+		for _, prefix := range revs.ExcludeRefs {
+			args = append(args, "--glob="+prefix+"*")
+		}
+		args = append(args, "--not")
+	}
+	args = append(args, "--end-of-options", revs.Include)
+	for _, rev := range revs.Exclude {
+		args = append(args, "^"+rev)
+	}
+	return streamLog(ctx, args, fn)
+}
+
+// streamLog runs git log with revision arguments and calls fn for each
+// commit as it is read
+func streamLog(ctx context.Context, revArgs []string, fn func(Commit) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := append([]string{"log", "--format=" + logFormat}, revArgs...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	readErr := readRecords(stdout, fn)
+	if readErr != nil {
+		// Stop git log before waiting for it
+		cancel()
+	}
+	waitErr := cmd.Wait()
+
+	switch {
+	case ctx.Err() != nil && readErr == nil:
+		return ctx.Err()
+	case readErr != nil:
+		return readErr
+	case waitErr != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", waitErr, msg)
+		}
+		return waitErr
+	}
+	return nil
+}
+
+// parseLog parses the output of git log --format=logFormat
+func parseLog(output []byte) ([]Commit, error) {
 	commits := []Commit{}
-	parts := strings.Split(string(output), "---\n")
+	err := readRecords(bytes.NewReader(output), func(c Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
 
-	for _, part := range parts {
-		if part == "" {
-			continue
+// readRecords reads NUL-terminated git log records from r and calls fn
+// with each parsed commit
+func readRecords(r io.Reader, fn func(Commit) error) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for {
+		record, err := reader.ReadString(0)
+		if err != nil && err != io.EOF {
+			return err
 		}
 
-		lines := strings.SplitN(strings.TrimSpace(part), "\n", 2)
-		if len(lines) < 2 {
-			continue
+		// git log separates records with a newline
+		record = strings.TrimLeft(strings.TrimSuffix(record, "\x00"), "\n")
+		if record != "" {
+			commit, parseErr := parseRecord(record)
+			if parseErr != nil {
+				return parseErr
+			}
+			if fnErr := fn(commit); fnErr != nil {
+				return fnErr
+			}
 		}
 
-		commits = append(commits, Commit{
-			Hash:    lines[0],
-			Message: strings.TrimSpace(lines[1]),
-		})
+		if err == io.EOF {
+			return nil
+		}
 	}
+}
 
-	return commits, nil
+func parseRecord(record string) (Commit, error) {
+	fields := strings.SplitN(record, "\x1e", logFields)
+	if len(fields) != logFields {
+		return Commit{}, fmt.Errorf("malformed git log record: %q", record)
+	}
+
+	authorDate, err := time.Parse(time.RFC3339, fields[4])
+	if err != nil {
+		return Commit{}, fmt.Errorf("invalid author date for %s: %w", fields[0], err)
+	}
+	committerDate, err := time.Parse(time.RFC3339, fields[7])
+	if err != nil {
+		return Commit{}, fmt.Errorf("invalid committer date for %s: %w", fields[0], err)
+	}
+
+	subject, body := splitSubject(fields[8])
+	return Commit{
+		Hash:      fields[0],
+		Parents:   append([]string{}, strings.Fields(fields[1])...),
+		Author:    Signature{Name: fields[2], Email: fields[3], Date: authorDate},
+		Committer: Signature{Name: fields[5], Email: fields[6], Date: committerDate},
+		Subject:   subject,
+		Body:      body,
+		Message:   strings.TrimSpace(fields[8]),
+	}, nil
+}
+
+// splitSubject mirrors git's %s and %b: the subject is the first paragraph
+// with its lines joined by spaces, the body is everything after it
+func splitSubject(message string) (subject, body string) {
+	message = strings.TrimLeft(message, "\n")
+	paragraph, rest, _ := strings.Cut(message, "\n\n")
+
+	lines := strings.Split(strings.TrimRight(paragraph, "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+
+	return strings.Join(lines, " "), strings.TrimLeft(rest, "\n")
+}
+
+// CurrentBranch returns the short name of the checked out branch, or an
+// empty string when HEAD is detached
+func CurrentBranch() (string, error) {
+	// Unlike rev-parse, symbolic-ref also names a branch with no commits yet
+	output, err := exec.Command("git", "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ResolveRevision returns the hash of the commit a revision names
+func ResolveRevision(rev string) (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Upstream returns the upstream of the checked out branch, such as
+// "origin/main", or an empty string when HEAD is detached or the branch
+// does not track anything
+func Upstream() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// MergeBase returns the best common ancestor of two commits
+func MergeBase(a, b string) (string, error) {
+	output, err := exec.Command("git", "merge-base", a, b).Output()
+	if err != nil {
+		return "", fmt.Errorf("no merge base between %s and %s", a, b)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IsShallow reports whether the repository is a shallow clone
+func IsShallow() (bool, error) {
+	output, err := exec.Command("git", "rev-parse", "--is-shallow-repository").Output()
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
+// CommonDir returns the absolute path of the git directory shared by all
+// worktrees of the current repository
+func CommonDir() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--git-common-dir").Output()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(strings.TrimSpace(string(output)))
+}
+
+// ConfigValue returns the value of a git config key, or an empty string
+// when it is not set. Outside a repository only the global and system
+// configs are read.
+func ConfigValue(key string) (string, error) {
+	output, err := exec.Command("git", "config", "--get", key).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// CommitWithMessage runs git commit with a message and any further
+// arguments, such as --all. The message is kept as written apart from
+// surrounding whitespace; git's output and the hooks' go to the terminal.
+func CommitWithMessage(message string, args []string) error {
+	f, err := os.CreateTemp("", "git-commit-linter-message-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(message + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("git", append([]string{"commit", "--cleanup=whitespace", "--file", f.Name()}, args...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git commit failed: %w", err)
+	}
+	return nil
+}
+
+// ChangedFiles returns the paths touched by a commit, relative to the
+// repository root
+func ChangedFiles(hash string) ([]string, error) {
+	return nameList("diff-tree", "-z", "--no-commit-id", "--name-only", "-r", "--root", hash)
+}
+
+// StagedFiles returns the paths staged for the next commit, which is what
+// a commit-msg hook is about to record
+func StagedFiles() ([]string, error) {
+	return nameList("diff", "-z", "--cached", "--name-only")
+}
+
+// nameList runs a git command printing NUL-terminated paths, which avoids
+// git's quoting of unusual file names
+func nameList(args ...string) ([]string, error) {
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
 }
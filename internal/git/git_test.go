@@ -0,0 +1,700 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-isatty"
+)
+
+func TestParseLog(t *testing.T) {
+	output := "abc123\x1e\x1eAda Lovelace\x1eada@example.com\x1e2024-01-02T03:04:05+00:00\x1e" +
+		"Ada Lovelace\x1eada@example.com\x1e2024-01-02T03:04:05+00:00\x1efeat: add parser\n\n" +
+		"Usage:\n---\nfoo: bar\n---\n\x00\n" +
+		"def456\x1eabc123 fff999\x1eBob\x1ebob@example.com\x1e2024-01-03T00:00:00+01:00\x1e" +
+		"Carol\x1ecarol@example.com\x1e2024-01-04T00:00:00Z\x1eMerge branch 'x'\nsecond line\n\nbody \x1e text\n\x00\n"
+
+	commits, err := parseLog([]byte(output))
+	if err != nil {
+		t.Fatalf("parseLog() error = %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+
+	first := commits[0]
+	if first.Hash != "abc123" || first.Parents == nil || len(first.Parents) != 0 {
+		t.Errorf("Unexpected root commit: %+v", first)
+	}
+	if first.Message != "feat: add parser\n\nUsage:\n---\nfoo: bar\n---" {
+		t.Errorf("Message containing --- lines was not preserved: %q", first.Message)
+	}
+	if first.Subject != "feat: add parser" || first.Body != "Usage:\n---\nfoo: bar\n---\n" {
+		t.Errorf("Unexpected subject/body: %q / %q", first.Subject, first.Body)
+	}
+	if first.Author.Name != "Ada Lovelace" || first.Author.Email != "ada@example.com" || first.Author.Date.Year() != 2024 {
+		t.Errorf("Unexpected author: %+v", first.Author)
+	}
+
+	second := commits[1]
+	if len(second.Parents) != 2 || second.Parents[1] != "fff999" {
+		t.Errorf("Expected two parents, got %v", second.Parents)
+	}
+	if second.Subject != "Merge branch 'x' second line" {
+		t.Errorf("Subject should join the first paragraph, got %q", second.Subject)
+	}
+	if second.Body != "body \x1e text\n" {
+		t.Errorf("Separator inside the message should be kept, got %q", second.Body)
+	}
+	if second.Committer.Name != "Carol" || second.Committer.Date.Day() != 4 {
+		t.Errorf("Unexpected committer: %+v", second.Committer)
+	}
+
+	if _, err := parseLog([]byte("abc123\x1etruncated\x00")); err == nil {
+		t.Error("Expected an error for a malformed record")
+	}
+}
+
+func TestGetCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("feat: first")
+	repo.commit("docs: second\n\n---\nfront: matter\n---\n")
+
+	commits, err := GetCommits("HEAD~1..HEAD")
+	if err != nil {
+		t.Fatalf("GetCommits() error = %v", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("Expected 1 commit, got %d: %+v", len(commits), commits)
+	}
+	if commits[0].Message != "docs: second\n\n---\nfront: matter\n---" {
+		t.Errorf("Unexpected message %q", commits[0].Message)
+	}
+	if len(commits[0].Parents) != 1 || commits[0].Author.Email != "test@example.com" {
+		t.Errorf("Unexpected metadata: %+v", commits[0])
+	}
+}
+
+// testRepo is a throwaway repository the tests chdir into
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+// newTestRepo creates an empty repository in a temp dir and makes it the
+// working directory for the rest of the test
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	repo := &testRepo{t: t, dir: dir}
+	repo.git("init", "-q", "-b", "main")
+	return repo
+}
+
+// git runs a git command in the repository and returns its output
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+	return string(output)
+}
+
+// commit creates an empty commit with the given message
+func (r *testRepo) commit(message string) {
+	r.t.Helper()
+	r.git("commit", "-q", "--allow-empty", "-m", message)
+}
+
+func TestDefaultRange(t *testing.T) {
+	repo := newTestRepo(t)
+
+	check := func(name string, sinceUpstream bool, wantRange, wantReason string) {
+		t.Helper()
+		for _, backend := range []string{BackendExec, BackendNative} {
+			r, err := Open(backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			choice, err := DefaultRange(r, sinceUpstream)
+			if wantRange == "" {
+				if err == nil {
+					t.Errorf("%s/%s: DefaultRange() = %+v, want an error", name, backend, choice)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s/%s: DefaultRange() error = %v", name, backend, err)
+				continue
+			}
+			if choice.Range != wantRange || !strings.Contains(choice.Reason, wantReason) {
+				t.Errorf("%s/%s: DefaultRange() = %+v, want range %q and a reason mentioning %q",
+					name, backend, choice, wantRange, wantReason)
+			}
+		}
+	}
+
+	if _, err := DefaultRange(ExecRepository{}, false); err == nil {
+		t.Error("DefaultRange() in an empty repository should fail")
+	}
+
+	repo.commit("feat: first")
+	first := strings.TrimSpace(repo.git("rev-parse", "HEAD"))
+	check("root commit", false, "HEAD", "first commit")
+
+	repo.commit("fix: second")
+	check("no remote", false, "HEAD^..HEAD", "last commit only")
+	check("no remote since upstream", true, "", "")
+
+	repo.git("update-ref", "refs/remotes/origin/main", first)
+	check("no upstream", false, first+"..HEAD", "main has no upstream")
+
+	repo.git("remote", "add", "origin", repo.dir)
+	repo.git("config", "branch.main.remote", "origin")
+	repo.git("config", "branch.main.merge", "refs/heads/main")
+	check("upstream", true, "origin/main..HEAD", "not yet on origin/main")
+
+	repo.git("checkout", "-q", "--detach")
+	check("detached", true, first+"..HEAD", "detached")
+
+	repo.git("checkout", "-q", "main")
+	repo.git("update-ref", "refs/remotes/origin/main", "HEAD")
+	repo.commit("docs: third")
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	repo.git("clone", "-q", "--depth", "1", "file://"+repo.dir, clone)
+	if err := os.Chdir(clone); err != nil {
+		t.Fatal(err)
+	}
+	repo.dir = clone
+	repo.git("checkout", "-q", "--detach")
+	repo.git("update-ref", "-d", "refs/remotes/origin/main")
+	check("shallow", false, "HEAD", "shallow clone")
+}
+
+func TestParseRefUpdates(t *testing.T) {
+	a := strings.Repeat("a", 40)
+	b := strings.Repeat("b", 40)
+
+	pushed, err := ParsePrePush(strings.NewReader(
+		"refs/heads/topic " + b + " refs/heads/topic " + a + "\n" +
+			"refs/heads/new " + b + " refs/heads/new " + zeroHash + "\n" +
+			"(delete) " + zeroHash + " refs/heads/old " + a + "\n"))
+	if err != nil {
+		t.Fatalf("ParsePrePush() error = %v", err)
+	}
+	received, err := ParsePreReceive(strings.NewReader(a + " " + b + " refs/heads/main\n"))
+	if err != nil {
+		t.Fatalf("ParsePreReceive() error = %v", err)
+	}
+
+	tests := []struct {
+		update RefUpdate
+		want   Revisions
+	}{
+		{pushed[0], Revisions{Include: b, Exclude: []string{a}}},
+		{pushed[1], Revisions{Include: b, ExcludeRefs: []string{RemoteRefs}}},
+		{pushed[2], Revisions{}},
+		{received[0], Revisions{Include: b, Exclude: []string{a}}},
+	}
+	for _, tt := range tests {
+		if got := tt.update.Revisions(RemoteRefs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Revisions() for %+v = %+v, want %+v", tt.update, got, tt.want)
+		}
+	}
+
+	if _, err := ParsePreReceive(strings.NewReader(a + " refs/heads/main\n")); err == nil {
+		t.Error("ParsePreReceive() should reject a malformed line")
+	}
+}
+
+// TestStreamRevisions_NewRef checks the revisions used for refs created by
+// a push, with the known remote refs both packed and loose
+func TestStreamRevisions_NewRef(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("feat: pushed")
+	repo.git("update-ref", "refs/remotes/origin/main", "HEAD")
+	repo.git("pack-refs", "--all")
+	repo.commit("feat: pushed elsewhere")
+	repo.git("update-ref", "refs/remotes/upstream/topic", "HEAD")
+	repo.commit("fix: not pushed")
+	head := strings.TrimSpace(repo.git("rev-parse", "HEAD"))
+	revs := RefUpdate{Ref: "refs/heads/main", Old: zeroHash, New: head}.Revisions(RemoteRefs)
+
+	for _, backend := range []string{BackendExec, BackendNative} {
+		r, err := Open(backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var subjects []string
+		err = r.StreamRevisions(context.Background(), revs, func(c Commit) error {
+			subjects = append(subjects, c.Subject)
+			return nil
+		})
+		r.Close()
+		if err != nil {
+			t.Errorf("%s: StreamRevisions() error = %v", backend, err)
+			continue
+		}
+		if want := []string{"fix: not pushed"}; !reflect.DeepEqual(subjects, want) {
+			t.Errorf("%s: StreamRevisions() = %q, want %q", backend, subjects, want)
+		}
+	}
+}
+
+func TestInstallHook_Layouts(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("feat: first")
+	root := repo.dir
+
+	chdir := func(dir string) {
+		t.Helper()
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	install := func(name, hookType, want string) {
+		t.Helper()
+		if err := InstallHook(hookType, HookOptions{}); err != nil {
+			t.Fatalf("%s: InstallHook() error = %v", name, err)
+		}
+		content, err := os.ReadFile(want)
+		if err != nil {
+			t.Fatalf("%s: hook not written to %s: %v", name, want, err)
+		}
+		if !strings.Contains(string(content), "git-commit-linter") {
+			t.Errorf("%s: unexpected hook content %q", name, content)
+		}
+	}
+
+	subdir := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(subdir)
+	install("subdirectory", HookCommitMsg, filepath.Join(root, ".git", "hooks", "commit-msg"))
+
+	worktree := filepath.Join(t.TempDir(), "wt")
+	repo.git("worktree", "add", "-q", worktree)
+	chdir(worktree)
+	install("worktree", HookPrePush, filepath.Join(root, ".git", "hooks", "pre-push"))
+
+	repo.git("-c", "protocol.file.allow=always", "submodule", "add", "-q", root, "sub")
+	chdir(filepath.Join(root, "sub"))
+	install("submodule", HookCommitMsg, filepath.Join(root, ".git", "modules", "sub", "hooks", "commit-msg"))
+
+	repo.git("config", "core.hooksPath", ".githooks")
+	chdir(subdir)
+	install("core.hooksPath", HookCommitMsg, filepath.Join(root, ".githooks", "commit-msg"))
+
+	bare := filepath.Join(t.TempDir(), "server.git")
+	repo.git("init", "-q", "--bare", bare)
+	chdir(bare)
+	install("bare", HookPreReceive, filepath.Join(bare, "hooks", "pre-receive"))
+}
+
+func TestInstallHook_Chain(t *testing.T) {
+	repo := newTestRepo(t)
+	hooks := filepath.Join(repo.dir, ".git", "hooks")
+	foreign := "#!/bin/sh\necho foreign\n"
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(hooks, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(hooks, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	write("commit-msg", foreign)
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		if err := InstallHook(HookCommitMsg, HookOptions{}); err == nil {
+			t.Error("InstallHook() without a terminal should refuse to touch a foreign hook")
+		}
+	}
+
+	if err := InstallHook(HookCommitMsg, HookOptions{Chain: ChainAfter}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	chained := read("commit-msg")
+	if !strings.HasPrefix(chained, foreign) || strings.Count(chained, hookBlockStart) != 1 {
+		t.Errorf("chained hook = %q", chained)
+	}
+
+	// Reinstalling only refreshes the block
+	if err := InstallHook(HookCommitMsg, HookOptions{ConfigPath: "/etc/lint.yaml"}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	updated := read("commit-msg")
+	if !strings.HasPrefix(updated, foreign) || strings.Count(updated, hookBlockStart) != 1 ||
+		!strings.Contains(updated, "--config '/etc/lint.yaml'") {
+		t.Errorf("reinstalled hook = %q", updated)
+	}
+
+	write("commit-msg", foreign)
+	if err := InstallHook(HookCommitMsg, HookOptions{Force: true}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	if read("commit-msg.backup") != foreign || strings.Contains(read("commit-msg"), "foreign") {
+		t.Error("InstallHook() with Force should replace the hook and keep a backup")
+	}
+
+	// The exact script of an older version is replaced without a backup
+	write("commit-msg", fmt.Sprintf(legacyHookScripts[HookCommitMsg], " --config '/etc/it'\\''s.yaml'"))
+	os.Remove(filepath.Join(hooks, "commit-msg.backup"))
+	if err := InstallHook(HookCommitMsg, HookOptions{}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	_, backupErr := os.Stat(filepath.Join(hooks, "commit-msg.backup"))
+	if backupErr == nil || !strings.Contains(read("commit-msg"), hookBlockStart) {
+		t.Errorf("InstallHook() should update a legacy hook in place, got %q", read("commit-msg"))
+	}
+
+	// A short hook that merely mentions the linter is someone else's
+	short := "#!/bin/sh\ngit-commit-linter lint-file \"$1\" || exit 1\nnotify-team \"$1\"\n"
+	write("commit-msg", short)
+	if err := InstallHook(HookCommitMsg, HookOptions{Chain: ChainAfter}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	if got := read("commit-msg"); !strings.HasPrefix(got, short) || !strings.Contains(got, hookBlockStart) {
+		t.Errorf("InstallHook() should chain with a hook that only mentions the linter, got %q", got)
+	}
+
+	write("pre-push", "#!/bin/sh\ncat > \"$PUSHED\"\n")
+	if err := InstallHook(HookPrePush, HookOptions{Chain: ChainAfter}); err == nil {
+		t.Error("InstallHook() should refuse to chain after a hook that reads stdin")
+	}
+	if err := InstallHook(HookPrePush, HookOptions{Yes: true}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+
+	// Run the chained hook with a stand-in linter that also reads stdin; the
+	// original hook must still see the ref updates
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "git-commit-linter"), []byte("#!/bin/sh\ncat > /dev/null\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pushed := filepath.Join(t.TempDir(), "pushed")
+	cmd := exec.Command(filepath.Join(hooks, "pre-push"), "origin", "url")
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"), "PUSHED="+pushed)
+	cmd.Stdin = strings.NewReader("refs/heads/main abc refs/heads/main def\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("pre-push hook failed: %v\n%s", err, output)
+	}
+	if got, _ := os.ReadFile(pushed); string(got) != "refs/heads/main abc refs/heads/main def\n" {
+		t.Errorf("original hook read %q from stdin", got)
+	}
+}
+
+func TestUninstallHook(t *testing.T) {
+	repo := newTestRepo(t)
+	hooks := filepath.Join(repo.dir, ".git", "hooks")
+	hook := func(name string) string { return filepath.Join(hooks, name) }
+	foreign := "#!/bin/sh\necho foreign\n"
+
+	// A chained hook keeps everything but the linter's block
+	if err := os.WriteFile(hook("pre-push"), []byte(foreign), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := InstallHook(HookPrePush, HookOptions{Chain: ChainBefore}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	if state, _ := HookStatus(HookPrePush); !state.Installed || !state.Chained {
+		t.Errorf("HookStatus() = %+v, want an installed, chained hook", state)
+	}
+	if removed, err := UninstallHook(HookPrePush); err != nil || !removed {
+		t.Fatalf("UninstallHook() = %v, %v, want true, nil", removed, err)
+	}
+	if content, _ := os.ReadFile(hook("pre-push")); string(content) != foreign {
+		t.Errorf("hook after uninstall = %q, want %q", content, foreign)
+	}
+
+	// A replaced hook is restored from its backup
+	if err := os.WriteFile(hook("commit-msg"), []byte(foreign), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := InstallHook(HookCommitMsg, HookOptions{Force: true}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	if removed, err := UninstallHook(HookCommitMsg); err != nil || !removed {
+		t.Fatalf("UninstallHook() = %v, %v, want true, nil", removed, err)
+	}
+	if content, _ := os.ReadFile(hook("commit-msg")); string(content) != foreign {
+		t.Errorf("hook after uninstall = %q, want %q", content, foreign)
+	}
+	if _, err := os.Stat(hook("commit-msg.backup")); !os.IsNotExist(err) {
+		t.Error("UninstallHook() should consume the backup it restores")
+	}
+
+	// A fresh install is removed, and the sample hook is never touched
+	if _, err := os.Stat(hook("update.sample")); err != nil {
+		t.Skip("git init did not create sample hooks")
+	}
+	if err := InstallHook(HookUpdate, HookOptions{}); err != nil {
+		t.Fatalf("InstallHook() error = %v", err)
+	}
+	if _, err := os.Stat(hook("update.sample")); err != nil {
+		t.Errorf("InstallHook() moved the sample hook: %v", err)
+	}
+	if removed, err := UninstallHook(HookUpdate); err != nil || !removed {
+		t.Fatalf("UninstallHook() = %v, %v, want true, nil", removed, err)
+	}
+	if _, err := os.Stat(hook("update")); !os.IsNotExist(err) {
+		t.Error("UninstallHook() should delete a hook that only ran the linter")
+	}
+	if _, err := os.Stat(hook("update.sample")); err != nil {
+		t.Errorf("sample hook missing after uninstall: %v", err)
+	}
+	if state, _ := HookStatus(HookUpdate); state.Exists {
+		t.Errorf("HookStatus() = %+v, want no hook", state)
+	}
+
+	if removed, err := UninstallHook(HookUpdate); err != nil || removed {
+		t.Errorf("UninstallHook() of a missing hook = %v, %v, want false, nil", removed, err)
+	}
+}
+
+func TestInsertYAML(t *testing.T) {
+	entry := "- id: new\n  name: new\n"
+	tests := []struct {
+		name    string
+		content string
+		path    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			path:    []string{"repos"},
+			want:    "repos:\n  - id: new\n    name: new\n",
+		},
+		{
+			name:    "indented sequence keeps comments and later keys",
+			content: "# top\nrepos:\n  - id: old # inline\n    name: old\n\n# next section\nfail_fast: true\n",
+			path:    []string{"repos"},
+			want:    "# top\nrepos:\n  - id: old # inline\n    name: old\n  - id: new\n    name: new\n\n# next section\nfail_fast: true\n",
+		},
+		{
+			name:    "indentless sequence",
+			content: "repos:\n- id: old\nfail_fast: true\n",
+			path:    []string{"repos"},
+			want:    "repos:\n- id: old\n- id: new\n  name: new\nfail_fast: true\n",
+		},
+		{
+			name:    "empty flow sequence",
+			content: "repos: []\n",
+			path:    []string{"repos"},
+			want:    "repos:\n  - id: new\n    name: new\n",
+		},
+		{
+			name:    "missing nested keys",
+			content: "pre-push:\n    commands:\n        test:\n            run: make test\n",
+			path:    []string{"commit-msg", "commands"},
+			want:    "pre-push:\n    commands:\n        test:\n            run: make test\ncommit-msg:\n  commands:\n    - id: new\n      name: new\n",
+		},
+		{
+			name:    "existing nested keys",
+			content: "commit-msg:\n    commands:\n        spell:\n            run: typos {1}\npre-push:\n    parallel: true\n",
+			path:    []string{"commit-msg", "commands"},
+			want:    "commit-msg:\n    commands:\n        spell:\n            run: typos {1}\n        - id: new\n          name: new\npre-push:\n    parallel: true\n",
+		},
+		{
+			name:    "flow mapping with content",
+			content: "repos: [{repo: local}]\n",
+			path:    []string{"repos"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := insertYAML(tt.content, tt.path, entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("insertYAML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("insertYAML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallManagedHook(t *testing.T) {
+	repo := newTestRepo(t)
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(repo.dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo.dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(repo.dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	if got := DetectHookManagers(repo.dir); len(got) != 0 {
+		t.Errorf("DetectHookManagers() = %v, want none", got)
+	}
+	write("lefthook.yml", "pre-commit:\n  commands:\n    fmt:\n      run: gofmt -l .\n")
+	write(".husky/pre-commit", "npm test\n")
+	if got := DetectHookManagers(repo.dir); !reflect.DeepEqual(got, []string{ManagerLefthook, ManagerHusky}) {
+		t.Errorf("DetectHookManagers() = %v, want [lefthook husky]", got)
+	}
+
+	if err := InstallManagedHook(ManagerLefthook, HookPrePush, HookOptions{}); err == nil {
+		t.Error("InstallManagedHook() should only support the commit-msg hook")
+	}
+
+	config := filepath.Join(repo.dir, "lint.yaml")
+	if err := InstallManagedHook(ManagerLefthook, HookCommitMsg, HookOptions{ConfigPath: config}); err != nil {
+		t.Fatalf("InstallManagedHook() error = %v", err)
+	}
+	want := "commit-msg:\n  commands:\n    git-commit-linter:\n      run: git-commit-linter --config 'lint.yaml' lint-file {1}\n"
+	if got := read("lefthook.yml"); !strings.HasSuffix(got, want) || !strings.HasPrefix(got, "pre-commit:") {
+		t.Errorf("lefthook.yml = %q", got)
+	}
+	// Installing again leaves the config alone
+	if err := InstallManagedHook(ManagerLefthook, HookCommitMsg, HookOptions{}); err != nil {
+		t.Fatalf("InstallManagedHook() error = %v", err)
+	}
+	if got := read("lefthook.yml"); strings.Count(got, "git-commit-linter:") != 1 {
+		t.Errorf("lefthook.yml after reinstall = %q", got)
+	}
+
+	if err := InstallManagedHook(ManagerHusky, HookCommitMsg, HookOptions{}); err != nil {
+		t.Fatalf("InstallManagedHook() error = %v", err)
+	}
+	if got := read(".husky/commit-msg"); !strings.Contains(got, `git-commit-linter lint-file "$1" || exit 1`) {
+		t.Errorf(".husky/commit-msg = %q", got)
+	}
+
+	if err := InstallManagedHook(ManagerPreCommit, HookCommitMsg, HookOptions{}); err != nil {
+		t.Fatalf("InstallManagedHook() error = %v", err)
+	}
+	if got := read(".pre-commit-config.yaml"); !strings.HasPrefix(got, "repos:\n  - repo: local\n") ||
+		!strings.Contains(got, "entry: git-commit-linter lint-file\n") {
+		t.Errorf(".pre-commit-config.yaml = %q", got)
+	}
+}
+
+func TestReword(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("Feat: first.")
+	repo.commit("fix: second")
+	repo.commit("docs third")
+	first := strings.TrimSpace(repo.git("rev-parse", "HEAD~2"))
+	third := strings.TrimSpace(repo.git("rev-parse", "HEAD"))
+
+	published, err := IsPublished(first)
+	if err != nil || published {
+		t.Errorf("IsPublished() = %v, %v, want false without remotes", published, err)
+	}
+	if ok, err := IsAncestor(first, "HEAD"); err != nil || !ok {
+		t.Errorf("IsAncestor() = %v, %v, want true", ok, err)
+	}
+	if ok, err := IsAncestor("HEAD", first); err != nil || ok {
+		t.Errorf("IsAncestor() = %v, %v, want false", ok, err)
+	}
+
+	// The test binary stands in for the sequence editor, see
+	// TestRewordSequenceEditor
+	t.Setenv("GIT_COMMIT_LINTER_TEST_SEQUENCE_EDITOR", "1")
+	editor := []string{os.Args[0], "-test.run=^TestRewordSequenceEditor$", "--"}
+	messages := map[string]string{
+		first: "feat: first\n\nWith a body",
+		third: "docs: third",
+	}
+	if err := Reword("", messages, false, editor); err != nil {
+		t.Fatalf("Reword() error = %v", err)
+	}
+
+	want := "docs: third\n\x00fix: second\n\x00feat: first\n\nWith a body\n\x00"
+	if got := repo.git("log", "--format=%B", "-z"); got != want {
+		t.Errorf("log after Reword() = %q, want %q", got, want)
+	}
+	if clean, err := IsWorkTreeClean(); err != nil || !clean {
+		t.Errorf("IsWorkTreeClean() = %v, %v, want true", clean, err)
+	}
+}
+
+// TestRewordSequenceEditor runs RewordTodo when TestReword calls the test
+// binary as its sequence editor
+func TestRewordSequenceEditor(t *testing.T) {
+	if os.Getenv("GIT_COMMIT_LINTER_TEST_SEQUENCE_EDITOR") == "" {
+		t.Skip("only run as the sequence editor of TestReword")
+	}
+	if err := RewordTodo(os.Args[len(os.Args)-1]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRewordTodo(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "abc123def"), []byte("feat: x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	todo := filepath.Join(t.TempDir(), "git-rebase-todo")
+	content := "pick abc123d feat x\npick 0011223 fix: y\n\n# Rebase instructions\n"
+	if err := os.WriteFile(todo, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(RewordPlanEnv, dir)
+
+	if err := RewordTodo(todo); err != nil {
+		t.Fatalf("RewordTodo() error = %v", err)
+	}
+	got, _ := os.ReadFile(todo)
+	want := "pick abc123d feat x\n" +
+		"exec git commit --amend --allow-empty --no-verify --quiet --cleanup=verbatim --file '" + filepath.Join(dir, "abc123def") + "'\n" +
+		"pick 0011223 fix: y\n\n# Rebase instructions\n"
+	if string(got) != want {
+		t.Errorf("RewordTodo() wrote %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,321 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/ui"
+)
+
+// Hook managers InstallManagedHook can configure instead of writing to the
+// hooks directory
+const (
+	ManagerPreCommit = "pre-commit"
+	ManagerLefthook  = "lefthook"
+	ManagerHusky     = "husky"
+)
+
+// HookManagers lists the hook managers InstallManagedHook supports
+var HookManagers = []string{ManagerPreCommit, ManagerLefthook, ManagerHusky}
+
+// managerFiles are the files, relative to the top of the work tree, that
+// show a repository uses a manager. The first one is created when none
+// exists.
+var managerFiles = map[string][]string{
+	ManagerPreCommit: {".pre-commit-config.yaml"},
+	ManagerLefthook:  {"lefthook.yml", "lefthook.yaml", ".lefthook.yml", ".lefthook.yaml"},
+	ManagerHusky:     {".husky"},
+}
+
+// managerNextSteps tells the user how to make the manager pick up the
+// change
+var managerNextSteps = map[string]string{
+	ManagerPreCommit: "pre-commit install --hook-type commit-msg",
+	ManagerLefthook:  "lefthook install",
+	ManagerHusky:     "npx husky",
+}
+
+// TopLevel returns the absolute path of the top of the work tree
+func TopLevel() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("not inside a git work tree")
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// DetectHookManagers returns the hook managers the work tree at root is set
+// up for
+func DetectHookManagers(root string) []string {
+	var found []string
+	for _, manager := range HookManagers {
+		if managerConfig(root, manager) != "" {
+			found = append(found, manager)
+		}
+	}
+	return found
+}
+
+// managerConfig returns the path of the manager's existing config, or an
+// empty string when there is none
+func managerConfig(root, manager string) string {
+	for _, name := range managerFiles[manager] {
+		path := filepath.Join(root, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// InstallManagedHook adds the linter to the config of a hook manager, to be
+// checked in with the rest of the repository. The config is patched as
+// text, so its comments and layout are kept; it is left alone if it
+// already mentions the linter. Only the commit-msg hook is supported.
+//
+// A relative opts.ConfigPath is taken as relative to the top of the work
+// tree, where the managers run hooks from; an absolute one inside the work
+// tree is made relative so the config works in every clone.
+//
+// Example usage:
+//
+//	if err := git.InstallManagedHook(git.ManagerLefthook, git.HookCommitMsg, git.HookOptions{}); err != nil {
+//	   fmt.Printf("Error installing hook: %v\n", err)
+//	 return
+//	}
+func InstallManagedHook(manager, hookType string, opts HookOptions) error {
+	if _, ok := managerFiles[manager]; !ok {
+		return fmt.Errorf("unknown hook manager %q (want one of %s)", manager, strings.Join(HookManagers, ", "))
+	}
+	if hookType != HookCommitMsg {
+		return fmt.Errorf("%s: only the %s hook can be installed through a hook manager", hookType, HookCommitMsg)
+	}
+
+	root, err := TopLevel()
+	if err != nil {
+		return err
+	}
+	if filepath.IsAbs(opts.ConfigPath) {
+		if rel, err := filepath.Rel(root, opts.ConfigPath); err == nil && !strings.HasPrefix(rel, "..") {
+			opts.ConfigPath = filepath.ToSlash(rel)
+		}
+	}
+
+	path := managerConfig(root, manager)
+	if path == "" {
+		path = filepath.Join(root, managerFiles[manager][0])
+	}
+	if manager == ManagerHusky {
+		path = filepath.Join(root, ".husky", hookType)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	existing := string(content)
+	if strings.Contains(existing, "git-commit-linter") {
+		ui.Info(fmt.Sprintf("%s already runs the linter", ui.Bold(path)))
+		return nil
+	}
+
+	var updated string
+	switch manager {
+	case ManagerPreCommit:
+		entry := preCommitEntry(opts)
+		if updated, err = insertYAML(existing, []string{"repos"}, entry); err != nil {
+			ui.CodeBlock("repos:\n" + indentYAML(entry, 2))
+		}
+	case ManagerLefthook:
+		entry := lefthookEntry(opts)
+		if updated, err = insertYAML(existing, []string{hookType, "commands"}, entry); err != nil {
+			ui.CodeBlock(hookType + ":\n  commands:\n" + indentYAML(entry, 4))
+		}
+	case ManagerHusky:
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		updated = "#!/bin/sh\n" + hookBlock(hookType, opts, false)
+		if existing != "" {
+			updated, err = chainHook(existing, hookType, opts, ChainAfter)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("cannot update %s: %w; add the snippet above by hand", path, err)
+	}
+
+	mode := os.FileMode(0644)
+	if manager == ManagerHusky {
+		mode = 0755
+	}
+	if err := os.WriteFile(path, []byte(updated), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	ui.Success(fmt.Sprintf("Added the linter to the %s %s hook", manager, hookType))
+	ui.Info(fmt.Sprintf("Updated %s; check it in and run %s", ui.Bold(path), ui.Bold(managerNextSteps[manager])))
+	return nil
+}
+
+// preCommitEntry is the repos entry that runs the installed linter from
+// the pre-commit framework, which passes the message file as an argument
+func preCommitEntry(opts HookOptions) string {
+	return fmt.Sprintf(`- repo: local
+  hooks:
+    - id: git-commit-linter
+      name: git-commit-linter
+      entry: git-commit-linter%s lint-file%s
+      language: system
+      stages: [commit-msg]
+`, configArg(opts.ConfigPath), fixArg(opts))
+}
+
+// lefthookEntry is the commands entry that runs the linter from lefthook,
+// where {1} is the first argument git passes to the hook
+func lefthookEntry(opts HookOptions) string {
+	return fmt.Sprintf(`git-commit-linter:
+  run: git-commit-linter%s lint-file%s {1}
+`, configArg(opts.ConfigPath), fixArg(opts))
+}
+
+func fixArg(opts HookOptions) string {
+	if opts.Fix {
+		return " --fix"
+	}
+	return ""
+}
+
+// insertYAML adds entry, a YAML snippet starting at column 0, at the end of
+// the block under the mapping keys in path, creating the keys that are
+// missing. It edits the text instead of decoding and encoding it, which
+// would lose comments, so it only understands block style along path; an
+// empty flow value such as "repos: []" is replaced.
+func insertYAML(content string, path []string, entry string) (string, error) {
+	var lines []string
+	if strings.TrimSpace(content) != "" {
+		lines = strings.Split(strings.TrimRight(content, "\n"), "\n")
+	}
+
+	start, end, parent := 0, len(lines), -1
+	for depth, key := range path {
+		indent := blockIndent(lines[start:end], parent)
+		found := -1
+		for i := start; i < end; i++ {
+			if lineIndent(lines[i]) == indent && isYAMLKey(lines[i], key) {
+				found = i
+				break
+			}
+		}
+
+		if found == -1 {
+			var added []string
+			for d, k := range path[depth:] {
+				added = append(added, strings.Repeat(" ", indent+2*d)+k+":")
+			}
+			added = append(added, indentYAML(entry, indent+2*len(path[depth:])))
+			return spliceLines(lines, lastContentLine(lines, start, end)+1, added), nil
+		}
+
+		switch value := yamlValue(lines[found], key); value {
+		case "":
+		case "[]", "{}":
+			lines[found] = strings.Repeat(" ", indent) + key + ":"
+		default:
+			return "", fmt.Errorf("%s is not a block (%s)", strings.Join(path[:depth+1], "."), value)
+		}
+
+		parent = indent
+		start = found + 1
+		end = blockEnd(lines, start, parent)
+	}
+
+	indent := blockIndent(lines[start:end], parent)
+	added := []string{indentYAML(entry, indent)}
+	return spliceLines(lines, lastContentLine(lines, start, end)+1, added), nil
+}
+
+// isYAMLContent reports whether a line holds more than whitespace or a
+// comment
+func isYAMLContent(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && !strings.HasPrefix(trimmed, "#")
+}
+
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isYAMLKey(line, key string) bool {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), key+":")
+	return ok && (rest == "" || rest[0] == ' ')
+}
+
+// yamlValue returns what follows the key on its line, without a comment
+func yamlValue(line, key string) string {
+	rest, _ := strings.CutPrefix(strings.TrimSpace(line), key+":")
+	if i := strings.Index(rest, " #"); i >= 0 {
+		rest = rest[:i]
+	}
+	return strings.TrimSpace(rest)
+}
+
+// blockIndent returns the indentation of the children in a block, or the
+// indentation new children should get when it has none
+func blockIndent(block []string, parent int) int {
+	for _, line := range block {
+		if isYAMLContent(line) {
+			return lineIndent(line)
+		}
+	}
+	if parent < 0 {
+		return 0
+	}
+	return parent + 2
+}
+
+// blockEnd returns the index of the first line after start that is not
+// part of the block under a key indented by parent. Sequence items may sit
+// at the key's own indentation.
+func blockEnd(lines []string, start, parent int) int {
+	for i := start; i < len(lines); i++ {
+		if !isYAMLContent(lines[i]) {
+			continue
+		}
+		indent := lineIndent(lines[i])
+		if indent < parent || indent == parent && !strings.HasPrefix(strings.TrimSpace(lines[i]), "-") {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// lastContentLine returns the last line of lines[start:end] that is not
+// blank or a comment, or start-1 when there is none. Comments trailing a
+// block usually introduce the next one.
+func lastContentLine(lines []string, start, end int) int {
+	for i := end - 1; i >= start; i-- {
+		if isYAMLContent(lines[i]) {
+			return i
+		}
+	}
+	return start - 1
+}
+
+func spliceLines(lines []string, at int, added []string) string {
+	result := append(append(append([]string{}, lines[:at]...), added...), lines[at:]...)
+	return strings.Join(result, "\n") + "\n"
+}
+
+// indentYAML indents every line of a snippet and drops its final newline
+func indentYAML(snippet string, indent int) string {
+	lines := strings.Split(strings.TrimRight(snippet, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = strings.Repeat(" ", indent) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
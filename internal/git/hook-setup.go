@@ -3,72 +3,428 @@ package git
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/mattn/go-isatty"
 	"github.com/randilt/git-commit-linter/internal/ui"
 )
 
-const hookContent = `#!/bin/sh
-commit_msg_file="$1"
+// Hook types InstallHook can write
+const (
+	HookCommitMsg        = "commit-msg"
+	HookPrepareCommitMsg = "prepare-commit-msg"
+	HookPrePush          = "pre-push"
+	HookPreReceive       = "pre-receive"
+	HookUpdate           = "update"
+)
+
+// HookTypes lists the hook types InstallHook supports
+var HookTypes = []string{HookCommitMsg, HookPrepareCommitMsg, HookPrePush, HookPreReceive, HookUpdate}
+
+// Where InstallHook puts the linter relative to an existing hook
+const (
+	ChainBefore = "before"
+	ChainAfter  = "after"
+)
 
-# Run the linter with the commit message file
-git-commit-linter lint-file "$commit_msg_file" || exit 1
-`
+// The linter's part of a hook sits between these lines, so it can be
+// updated or removed without touching the rest of the script
+const (
+	hookBlockStart = "# >>> git-commit-linter >>>"
+	hookBlockEnd   = "# <<< git-commit-linter <<<"
+)
+
+// hookCommands are the linter invocations each hook runs. They pass on the
+// hook's arguments and fail the hook when linting fails.
+var hookCommands = map[string]string{
+	HookCommitMsg:        `git-commit-linter%s lint-file "$1" || exit 1`,
+	HookPrepareCommitMsg: `git-commit-linter%s hook prepare-commit-msg "$@" || exit 1`,
+	HookPrePush:          `git-commit-linter%s hook pre-push "$@" || exit 1`,
+	HookPreReceive:       `git-commit-linter%s hook pre-receive || exit 1`,
+	HookUpdate:           `git-commit-linter%s hook update "$1" "$2" "$3" || exit 1`,
+}
+
+// stdinHooks are the hooks git feeds ref updates on stdin, which can only
+// be read once
+var stdinHooks = map[string]bool{HookPrePush: true, HookPreReceive: true}
+
+// HookOptions controls how InstallHook treats an existing hook
+type HookOptions struct {
+	// ConfigPath, if not empty, should be absolute; the hook passes it to
+	// the linter
+	ConfigPath string
+	// Chain keeps an existing hook and runs the linter before or after it
+	Chain string
+	// Force overwrites an existing hook, keeping a backup
+	Force bool
+	// Yes chains before an existing hook instead of asking
+	Yes bool
+	// Fix makes the commit-msg hook apply safe fixes to the message
+	Fix bool
+}
+
+// configArg returns the shell-quoted --config flag for configPath, or an
+// empty string when the default config is used
+func configArg(configPath string) string {
+	if configPath == "" {
+		return ""
+	}
+	return " --config " + ShellQuote(configPath)
+}
+
+// ShellQuote quotes s as a single word for sh
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// hookBlock returns the marked block that runs the linter. When other
+// commands follow it in the same hook, stdin is saved and handed on, since
+// the linter would otherwise consume the ref updates.
+func hookBlock(hookType string, opts HookOptions, followed bool) string {
+	command := fmt.Sprintf(hookCommands[hookType], configArg(opts.ConfigPath))
+	if opts.Fix && hookType == HookCommitMsg {
+		command = strings.Replace(command, " lint-file ", " lint-file --fix ", 1)
+	}
+
+	var b strings.Builder
+	b.WriteString(hookBlockStart + "\n")
+	b.WriteString("# Managed by git-commit-linter; changes inside this block are overwritten\n")
+	if stdinHooks[hookType] && followed {
+		command = strings.Replace(command, " || exit 1", ` < "$git_commit_linter_stdin" || { rm -f "$git_commit_linter_stdin"; exit 1; }`, 1)
+		b.WriteString("git_commit_linter_stdin=$(mktemp) || exit 1\n")
+		b.WriteString("cat > \"$git_commit_linter_stdin\"\n")
+		b.WriteString(command + "\n")
+		b.WriteString("# Hand the ref updates on to the rest of the hook\n")
+		b.WriteString("exec < \"$git_commit_linter_stdin\"\n")
+		b.WriteString("rm -f \"$git_commit_linter_stdin\"\n")
+	} else {
+		b.WriteString(command + "\n")
+	}
+	b.WriteString(hookBlockEnd + "\n")
+	return b.String()
+}
+
+// findHookBlock returns the byte range of the marked block in a hook,
+// including the marker lines and the newline after the end marker
+func findHookBlock(content string) (start, end int, ok bool) {
+	start = strings.Index(content, hookBlockStart)
+	if start < 0 {
+		return 0, 0, false
+	}
+	rel := strings.Index(content[start:], hookBlockEnd)
+	if rel < 0 {
+		return 0, 0, false
+	}
+	end = start + rel + len(hookBlockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return start, end, true
+}
 
-// InstallHook installs the commit-msg hook in the current git repository
+// legacyHookScripts are the whole scripts written by versions that did not
+// use a marked block, with %s where the --config flag went. Those versions
+// only installed commit-msg hooks.
+var legacyHookScripts = map[string]string{
+	HookCommitMsg: "#!/bin/sh\ncommit_msg_file=\"$1\"\n\n# Run the linter with the commit message file\n" +
+		"git-commit-linter%s lint-file \"$commit_msg_file\" || exit 1\n",
+}
+
+// legacyConfigArg matches the optional --config flag of a legacy script
+const legacyConfigArg = `(?: --config '(?:[^']|'\\'')*')?`
+
+// isLinterHook reports whether a hook was written by the linter: it has the
+// marked block, or it is exactly the script an older version wrote. Any
+// other hook, even one that mentions the linter, belongs to someone else.
+func isLinterHook(content, hookType string) bool {
+	if _, _, ok := findHookBlock(content); ok {
+		return true
+	}
+	legacy, ok := legacyHookScripts[hookType]
+	if !ok {
+		return false
+	}
+	parts := strings.Split(legacy, "%s")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	pattern := regexp.MustCompile("^" + strings.Join(parts, legacyConfigArg) + "$")
+	return pattern.MatchString(content)
+}
+
+// updateHookBlock regenerates the block in a hook that already has one
+func updateHookBlock(content, hookType string, opts HookOptions) string {
+	start, end, _ := findHookBlock(content)
+	followed := strings.TrimSpace(content[end:]) != ""
+	return content[:start] + hookBlock(hookType, opts, followed) + content[end:]
+}
+
+// chainHook adds the block to a foreign hook, before its first command or
+// at the end
+func chainHook(content, hookType string, opts HookOptions, position string) (string, error) {
+	if position == ChainAfter {
+		if stdinHooks[hookType] {
+			return "", fmt.Errorf("the existing %s hook would read the ref updates on stdin before the linter; use --chain=before", hookType)
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content + hookBlock(hookType, opts, false), nil
+	}
+
+	// Keep the shebang line first
+	insert := 0
+	if strings.HasPrefix(content, "#!") {
+		if i := strings.Index(content, "\n"); i >= 0 {
+			insert = i + 1
+		} else {
+			content += "\n"
+			insert = len(content)
+		}
+	}
+	return content[:insert] + hookBlock(hookType, opts, true) + content[insert:], nil
+}
+
+// endsWithExit reports whether the last command of a script is exit or
+// exec, after which an appended block would never run
+func endsWithExit(content string) bool {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return line == "exit" || strings.HasPrefix(line, "exit ") || strings.HasPrefix(line, "exec ")
+	}
+	return false
+}
+
+// HooksDir returns the absolute path of the directory git runs hooks from.
+// It follows core.hooksPath and finds the shared hooks of linked worktrees
+// and the hooks of submodules, from anywhere inside the repository.
+func HooksDir() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("not a git repository (or any of the parent directories)")
+	}
+	return filepath.Abs(strings.TrimSpace(string(output)))
+}
+
+// InstallHook installs a hook of the given type in the hooks directory of
+// the current git repository. Installing again only updates the linter's
+// block. A hook written by another tool is chained with when opts.Chain or
+// opts.Yes is set and replaced when opts.Force is set; otherwise the user
+// is asked, or an error is returned when stdin is not a terminal.
 //
 // Example usage:
 //
-//	if err := git.InstallHook(); err != nil {
+//	if err := git.InstallHook(git.HookPrePush, git.HookOptions{Chain: git.ChainBefore}); err != nil {
 //	   fmt.Printf("Error installing hook: %v\n", err)
 //	 return
 //	}
 //
 // Prints a success message if the hook is installed successfully
-func InstallHook() error {
-	// Check if inside a git directory
-	if _, err := os.Stat(".git"); os.IsNotExist(err) {
-		return fmt.Errorf("not a git repository (or any of the parent directories)")
+func InstallHook(hookType string, opts HookOptions) error {
+	if _, ok := hookCommands[hookType]; !ok {
+		return fmt.Errorf("unknown hook type %q (want one of %s)", hookType, strings.Join(HookTypes, ", "))
+	}
+	switch opts.Chain {
+	case "", ChainBefore, ChainAfter:
+	default:
+		return fmt.Errorf("unknown chain position %q (want %s or %s)", opts.Chain, ChainBefore, ChainAfter)
+	}
+
+	hooksDir, err := HooksDir()
+	if err != nil {
+		return err
+	}
+	// A core.hooksPath directory may not exist yet
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
 	}
 
-	hookPath := filepath.Join(".git", "hooks", "commit-msg")
-	samplePath := filepath.Join(".git", "hooks", "commit-msg.sample")
+	hookPath := filepath.Join(hooksDir, hookType)
+	script := "#!/bin/sh\n" + hookBlock(hookType, opts, false)
+	action := "installed"
 
 	// Check if hook already exists
-	if _, err := os.Stat(hookPath); err == nil {
-		// Read existing hook content
-		content, err := os.ReadFile(hookPath)
-		if err != nil {
-			return fmt.Errorf("failed to read existing hook: %w", err)
+	content, err := os.ReadFile(hookPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read existing hook: %w", err)
+	}
+	if err == nil {
+		existing := string(content)
+		position := opts.Chain
+		if !isLinterHook(existing, hookType) && !opts.Force && position == "" {
+			if position, err = askChainPosition(hookType, existing, opts.Yes); err != nil || position == "" {
+				return err
+			}
 		}
 
-		// Prompt user for action
-		ui.Section("Existing Hook")
-		ui.Warning("A commit-msg hook already exists")
-		ui.Info("Current hook content:")
-		ui.CodeBlock(string(content))
-
-		response := ui.Prompt("Do you want to overwrite it? [y/N]:")
-		if response != "y" && response != "Y" {
-			ui.Error("Hook installation cancelled")
-			return nil
+		switch {
+		case isLinterHook(existing, hookType):
+			if _, _, ok := findHookBlock(existing); ok {
+				script = updateHookBlock(existing, hookType, opts)
+			}
+			action = "updated"
+		case opts.Force || position == "overwrite":
+			backupPath := hookPath + ".backup"
+			if err := os.WriteFile(backupPath, content, 0755); err != nil {
+				return fmt.Errorf("failed to back up existing hook: %w", err)
+			}
+			ui.Info(fmt.Sprintf("Existing hook saved to %s", ui.Bold(backupPath)))
+		default:
+			if script, err = chainHook(existing, hookType, opts, position); err != nil {
+				return err
+			}
+			if position == ChainAfter && endsWithExit(existing) {
+				ui.Warning("The existing hook ends with exit or exec, so the linter added after it may never run")
+			}
+			action = "chained " + position + " the existing hook"
 		}
 	}
 
 	// Create or overwrite the hook file
-	err := os.WriteFile(hookPath, []byte(hookContent), 0755)
+	err = os.WriteFile(hookPath, []byte(script), 0755)
 	if err != nil {
 		return fmt.Errorf("failed to write hook file: %w", err)
 	}
 
-	// If sample hook exists, keep a backup
-	if _, err := os.Stat(samplePath); err == nil {
-		backupPath := samplePath + ".backup"
-		if err := os.Rename(samplePath, backupPath); err != nil {
-			ui.Warning(fmt.Sprintf("Could not backup sample hook: %v", err))
+	ui.Success(fmt.Sprintf("Git %s hook %s successfully!", hookType, action))
+	ui.Info(fmt.Sprintf("Hook written to %s", ui.Bold(hookPath)))
+	return nil
+}
+
+// askChainPosition asks what to do with a hook written by another tool. It
+// returns ChainBefore, ChainAfter, "overwrite", or an empty string when the
+// user cancels.
+func askChainPosition(hookType, existing string, yes bool) (string, error) {
+	if yes {
+		return ChainBefore, nil
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return "", fmt.Errorf("a %s hook already exists; use --chain=before, --chain=after or --force", hookType)
+	}
+
+	ui.Section("Existing Hook")
+	ui.Warning(fmt.Sprintf("A %s hook already exists", hookType))
+	ui.Info("Current hook content:")
+	ui.CodeBlock(existing)
+
+	switch ui.Prompt("Run the linter [b]efore or [a]fter it, [o]verwrite it, or cancel? [b/a/o/N]:") {
+	case "b", "B":
+		return ChainBefore, nil
+	case "a", "A":
+		return ChainAfter, nil
+	case "o", "O":
+		return "overwrite", nil
+	}
+	ui.Error("Hook installation cancelled")
+	return "", nil
+}
+
+// HookState describes what is installed for one hook type
+type HookState struct {
+	Type string
+	Path string
+	// Exists is set when there is a hook of this type at all
+	Exists bool
+	// Installed is set when the hook runs the linter
+	Installed bool
+	// Chained is set when the hook also runs commands of another tool
+	Chained bool
+}
+
+// HookStatus reports whether the linter is installed as a hook of the given
+// type
+func HookStatus(hookType string) (HookState, error) {
+	hooksDir, err := HooksDir()
+	if err != nil {
+		return HookState{}, err
+	}
+
+	state := HookState{Type: hookType, Path: filepath.Join(hooksDir, hookType)}
+	content, err := os.ReadFile(state.Path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	existing := string(content)
+	state.Exists = true
+	state.Installed = isLinterHook(existing, hookType)
+	if start, end, ok := findHookBlock(existing); ok {
+		state.Chained = !isEmptyScript(existing[:start] + existing[end:])
+	}
+	return state, nil
+}
+
+// UninstallHook removes the linter from a hook. A chained hook keeps the
+// other tool's commands; a hook that only ran the linter is deleted and
+// replaced by the one install-hook --force saved, if any. It reports
+// whether anything was removed.
+func UninstallHook(hookType string) (bool, error) {
+	if _, ok := hookCommands[hookType]; !ok {
+		return false, fmt.Errorf("unknown hook type %q (want one of %s)", hookType, strings.Join(HookTypes, ", "))
+	}
+	state, err := HookStatus(hookType)
+	if err != nil {
+		return false, err
+	}
+
+	if state.Installed {
+		content, err := os.ReadFile(state.Path)
+		if err != nil {
+			return false, err
+		}
+
+		if state.Chained {
+			start, end, _ := findHookBlock(string(content))
+			rest := string(content[:start]) + string(content[end:])
+			if err := os.WriteFile(state.Path, []byte(rest), 0755); err != nil {
+				return false, fmt.Errorf("failed to update hook: %w", err)
+			}
+		} else {
+			if err := os.Remove(state.Path); err != nil {
+				return false, fmt.Errorf("failed to remove hook: %w", err)
+			}
+			if err := restoreFile(state.Path+".backup", state.Path); err != nil {
+				return false, err
+			}
 		}
 	}
 
-	ui.Success("Git commit-msg hook installed successfully!")
+	return state.Installed, nil
+}
+
+// restoreFile moves a backup back into place if it exists and nothing has
+// taken its place
+func restoreFile(backup, path string) error {
+	if _, err := os.Stat(backup); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.Rename(backup, path); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, err)
+	}
 	return nil
 }
+
+// isEmptyScript reports whether a script has nothing but a shebang,
+// comments and blank lines
+func isEmptyScript(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,802 @@
+package git
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nativeRepository reads commits straight from the .git directory, without
+// running the git binary. It understands loose objects, packfiles, loose and
+// packed refs, linked worktrees, shallow clones and push quarantines.
+type nativeRepository struct {
+	gitDir    string
+	commonDir string
+	objects   *objectStore
+	shallow   map[string]bool
+	commits   map[string]*Commit
+}
+
+var (
+	hashPattern      = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+	shortHashPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,39}$`)
+	revSuffixPattern = regexp.MustCompile(`^(?:\^\{(commit|)\}|\^([0-9]*)|~([0-9]*))`)
+)
+
+// OpenNative opens the repository containing the current directory (or
+// $GIT_DIR) using the native backend
+func OpenNative() (Repository, error) {
+	gitDir, err := findGitDir()
+	if err != nil {
+		return nil, err
+	}
+	return openNativeAt(gitDir)
+}
+
+func openNativeAt(gitDir string) (*nativeRepository, error) {
+	r := &nativeRepository{
+		gitDir:    gitDir,
+		commonDir: gitDir,
+		shallow:   make(map[string]bool),
+		commits:   make(map[string]*Commit),
+	}
+
+	// Linked worktrees keep their objects and refs in the main repository
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		dir := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(gitDir, dir)
+		}
+		r.commonDir = filepath.Clean(dir)
+	}
+
+	// Hooks run during a push, such as pre-receive, read the pushed objects
+	// from a quarantine directory git passes in the environment
+	objectsDir := filepath.Join(r.commonDir, "objects")
+	if dir := os.Getenv("GIT_OBJECT_DIRECTORY"); dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		objectsDir = abs
+	}
+	alternates := filepath.SplitList(os.Getenv("GIT_ALTERNATE_OBJECT_DIRECTORIES"))
+	objects, err := openObjectStore(objectsDir, alternates)
+	if err != nil {
+		return nil, err
+	}
+	r.objects = objects
+
+	if shallow, err := os.ReadFile(filepath.Join(r.commonDir, "shallow")); err == nil {
+		for _, line := range strings.Fields(string(shallow)) {
+			r.shallow[line] = true
+		}
+	}
+
+	return r, nil
+}
+
+// findGitDir locates the git directory for the current working directory,
+// following "gitdir:" files used by worktrees and submodules
+func findGitDir() (string, error) {
+	if dir := os.Getenv("GIT_DIR"); dir != "" {
+		return filepath.Abs(dir)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dotGit, nil
+			}
+			return readGitFile(dotGit)
+		}
+		if isGitDir(dir) {
+			// Bare repository
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("not a git repository (or any of the parent directories)")
+		}
+		dir = parent
+	}
+}
+
+func readGitFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+	if !ok {
+		return "", fmt.Errorf("invalid gitfile format: %s", path)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(path), dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+func isGitDir(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Commits returns the commits in a range such as "A..B" or a single
+// revision, newest first like git log
+func (r *nativeRepository) Commits(commitRange string) ([]Commit, error) {
+	commits := []Commit{}
+	err := r.StreamCommits(context.Background(), commitRange, func(c Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// StreamCommits walks the range and calls fn for each commit as soon as it
+// is reached
+func (r *nativeRepository) StreamCommits(ctx context.Context, commitRange string, fn func(Commit) error) error {
+	if strings.Contains(commitRange, "...") || strings.ContainsAny(commitRange, " \t") {
+		return fmt.Errorf("native backend does not support range %q", commitRange)
+	}
+
+	var include, exclude string
+	if from, to, ok := strings.Cut(commitRange, ".."); ok {
+		include = to
+		exclude = from
+		if exclude == "" {
+			exclude = "HEAD"
+		}
+	} else {
+		include = commitRange
+	}
+	if include == "" {
+		include = "HEAD"
+	}
+
+	tip, err := r.resolveRevision(include)
+	if err != nil {
+		return err
+	}
+
+	hidden := make(map[string]bool)
+	if exclude != "" {
+		base, err := r.resolveRevision(exclude)
+		if err != nil {
+			return err
+		}
+		if err := r.markAncestors(base, hidden); err != nil {
+			return err
+		}
+	}
+
+	return r.walk(ctx, tip, hidden, fn)
+}
+
+// StreamRevisions walks the commits reachable from revs.Include that no
+// excluded revision or ref reaches
+func (r *nativeRepository) StreamRevisions(ctx context.Context, revs Revisions, fn func(Commit) error) error {
+	tip, err := r.resolveRevision(revs.Include)
+	if err != nil {
+		return err
+	}
+
+	hidden := make(map[string]bool)
+	for _, rev := range revs.Exclude {
+		base, err := r.resolveRevision(rev)
+		if err != nil {
+			return err
+		}
+		if err := r.markAncestors(base, hidden); err != nil {
+			return err
+		}
+	}
+	for _, prefix := range revs.ExcludeRefs {
+		refs, err := r.listRefs(prefix)
+		if err != nil {
+			return err
+		}
+		for _, hash := range refs {
+			// Like git log --glob, skip refs that do not lead to a commit
+			base, err := r.peelToCommit(hash)
+			if err != nil {
+				continue
+			}
+			if err := r.markAncestors(base, hidden); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.walk(ctx, tip, hidden, fn)
+}
+
+// CurrentBranch returns the branch HEAD points to, or an empty string when
+// HEAD is detached
+func (r *nativeRepository) CurrentBranch() (string, error) {
+	content, err := os.ReadFile(filepath.Join(r.gitDir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "ref: ")
+	if !ok {
+		return "", nil
+	}
+	return strings.TrimPrefix(target, "refs/heads/"), nil
+}
+
+// CommonDir returns the directory shared by all worktrees of the repository
+func (r *nativeRepository) CommonDir() (string, error) {
+	return filepath.Abs(r.commonDir)
+}
+
+// ResolveRevision resolves a revision to a commit hash
+func (r *nativeRepository) ResolveRevision(rev string) (string, error) {
+	return r.resolveRevision(rev)
+}
+
+// Upstream reads the branch.<name>.remote and branch.<name>.merge settings
+// of the checked out branch. Custom fetch refspecs are not applied.
+func (r *nativeRepository) Upstream() (string, error) {
+	branch, err := r.CurrentBranch()
+	if err != nil || branch == "" {
+		return "", err
+	}
+
+	cfg, err := readConfig(filepath.Join(r.commonDir, "config"))
+	if err != nil {
+		return "", err
+	}
+	remote := cfg["branch."+branch+".remote"]
+	merge := strings.TrimPrefix(cfg["branch."+branch+".merge"], "refs/heads/")
+	if remote == "" || merge == "" {
+		return "", nil
+	}
+
+	upstream := remote + "/" + merge
+	if remote == "." {
+		upstream = merge
+	}
+	// Like git, treat an upstream that no longer exists as none
+	if _, err := r.resolveName(upstream); err != nil {
+		return "", nil
+	}
+	return upstream, nil
+}
+
+// MergeBase returns the newest commit reachable from both a and b. This is
+// the merge base git reports except for criss-cross merges, where git may
+// pick another of several equally good bases.
+func (r *nativeRepository) MergeBase(a, b string) (string, error) {
+	from, err := r.resolveRevision(a)
+	if err != nil {
+		return "", err
+	}
+	to, err := r.resolveRevision(b)
+	if err != nil {
+		return "", err
+	}
+
+	reachable := make(map[string]bool)
+	if err := r.markAncestors(from, reachable); err != nil {
+		return "", err
+	}
+
+	base := ""
+	errFound := errors.New("found")
+	err = r.walk(context.Background(), to, nil, func(c Commit) error {
+		if reachable[c.Hash] {
+			base = c.Hash
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && err != errFound {
+		return "", err
+	}
+	if base == "" {
+		return "", fmt.Errorf("no merge base between %s and %s", a, b)
+	}
+	return base, nil
+}
+
+// IsShallow reports whether the clone has a shallow file
+func (r *nativeRepository) IsShallow() (bool, error) {
+	return len(r.shallow) > 0, nil
+}
+
+// Close closes the packfiles opened while reading objects
+func (r *nativeRepository) Close() error {
+	return r.objects.close()
+}
+
+// readConfig reads a git config file into a map keyed like "git config
+// --list": section and key names are lower case, subsections keep their
+// case. Includes and multi-valued keys are not supported; the last value
+// wins.
+func readConfig(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			header := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			name, sub, ok := strings.Cut(header, " ")
+			section = strings.ToLower(name)
+			if ok {
+				section += "." + strings.Trim(strings.TrimSpace(sub), `"`)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			// A key without a value is a boolean true
+			value = "true"
+		}
+		value = strings.TrimSpace(value)
+		if i := strings.IndexAny(value, "#;"); i >= 0 && !strings.HasPrefix(value, `"`) {
+			value = strings.TrimSpace(value[:i])
+		}
+		values[section+"."+strings.ToLower(strings.TrimSpace(key))] = strings.Trim(value, `"`)
+	}
+	return values, scanner.Err()
+}
+
+// markAncestors adds start and all of its ancestors to seen
+func (r *nativeRepository) markAncestors(start string, seen map[string]bool) error {
+	stack := []string{start}
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		// Hidden commits are never visited, so they are not cached
+		commit, err := r.readCommit(hash)
+		if err != nil {
+			return err
+		}
+		stack = append(stack, commit.Parents...)
+	}
+	return nil
+}
+
+// walk visits the ancestors of tip that are not hidden, ordered by
+// committer date with the newest first
+func (r *nativeRepository) walk(ctx context.Context, tip string, hidden map[string]bool, fn func(Commit) error) error {
+	if hidden[tip] {
+		return nil
+	}
+
+	queue := &commitQueue{}
+	queued := map[string]bool{tip: true}
+	first, err := r.commit(tip)
+	if err != nil {
+		return err
+	}
+	heap.Push(queue, first)
+
+	for queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		commit := heap.Pop(queue).(*Commit)
+		if err := fn(*commit); err != nil {
+			return err
+		}
+		// Each commit is visited once, so keep memory flat on long walks
+		delete(r.commits, commit.Hash)
+
+		for _, parent := range commit.Parents {
+			if hidden[parent] || queued[parent] {
+				continue
+			}
+			queued[parent] = true
+			c, err := r.commit(parent)
+			if err != nil {
+				return err
+			}
+			heap.Push(queue, c)
+		}
+	}
+
+	return nil
+}
+
+// commit reads and caches a commit object
+func (r *nativeRepository) commit(hash string) (*Commit, error) {
+	if c, ok := r.commits[hash]; ok {
+		return c, nil
+	}
+	c, err := r.readCommit(hash)
+	if err != nil {
+		return nil, err
+	}
+	r.commits[hash] = c
+	return c, nil
+}
+
+// readCommit reads a commit object, using the cache but not adding to it
+func (r *nativeRepository) readCommit(hash string) (*Commit, error) {
+	if c, ok := r.commits[hash]; ok {
+		return c, nil
+	}
+
+	objType, data, err := r.objects.read(hash)
+	if err != nil {
+		return nil, err
+	}
+	if objType != "commit" {
+		return nil, fmt.Errorf("object %s is a %s, not a commit", hash, objType)
+	}
+
+	c, err := parseCommitObject(hash, data)
+	if err != nil {
+		return nil, err
+	}
+	if r.shallow[hash] {
+		// Parents of shallow commits are not in the clone
+		c.Parents = []string{}
+	}
+	return c, nil
+}
+
+// parseCommitObject decodes the content of a commit object
+func parseCommitObject(hash string, data []byte) (*Commit, error) {
+	headers, message, _ := strings.Cut(string(data), "\n\n")
+	c := &Commit{Hash: hash, Parents: []string{}}
+
+	for _, line := range strings.Split(headers, "\n") {
+		if strings.HasPrefix(line, " ") {
+			// Continuation of a multi-line header such as gpgsig
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "parent":
+			c.Parents = append(c.Parents, value)
+		case "author", "committer":
+			sig, err := parseSignature(value)
+			if err != nil {
+				return nil, fmt.Errorf("commit %s: %w", hash, err)
+			}
+			if key == "author" {
+				c.Author = sig
+			} else {
+				c.Committer = sig
+			}
+		}
+	}
+
+	c.Subject, c.Body = splitSubject(message)
+	c.Message = strings.TrimSpace(message)
+	return c, nil
+}
+
+// parseSignature decodes "Name <email> 1700000000 +0100"
+func parseSignature(value string) (Signature, error) {
+	open := strings.LastIndex(value, "<")
+	end := strings.LastIndex(value, ">")
+	if open < 0 || end < open {
+		return Signature{}, fmt.Errorf("malformed signature %q", value)
+	}
+
+	sig := Signature{
+		Name:  strings.TrimSpace(value[:open]),
+		Email: value[open+1 : end],
+	}
+
+	fields := strings.Fields(value[end+1:])
+	if len(fields) != 2 {
+		return Signature{}, fmt.Errorf("malformed signature date %q", value)
+	}
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return Signature{}, fmt.Errorf("malformed signature date %q", value)
+	}
+	zone, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Signature{}, fmt.Errorf("malformed signature zone %q", value)
+	}
+	offset := (zone/100*60 + zone%100) * 60
+	sig.Date = time.Unix(seconds, 0).In(time.FixedZone(fields[1], offset))
+
+	return sig, nil
+}
+
+// resolveRevision turns a revision such as "HEAD~2", "v1.0^", "main" or an
+// abbreviated hash into a commit hash
+func (r *nativeRepository) resolveRevision(rev string) (string, error) {
+	name := rev
+	if i := strings.IndexAny(rev, "^~"); i >= 0 {
+		name = rev[:i]
+	}
+	suffix := rev[len(name):]
+
+	hash, err := r.resolveName(name)
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q: %w", rev, err)
+	}
+	if hash, err = r.peelToCommit(hash); err != nil {
+		return "", err
+	}
+
+	for suffix != "" {
+		m := revSuffixPattern.FindStringSubmatch(suffix)
+		if m == nil {
+			return "", fmt.Errorf("unsupported revision syntax %q", rev)
+		}
+		suffix = suffix[len(m[0]):]
+
+		switch {
+		case strings.HasPrefix(m[0], "^{"):
+			// Already peeled to a commit
+		case strings.HasPrefix(m[0], "^"):
+			n := 1
+			if m[2] != "" {
+				n, _ = strconv.Atoi(m[2])
+			}
+			if n == 0 {
+				continue
+			}
+			commit, err := r.commit(hash)
+			if err != nil {
+				return "", err
+			}
+			if n > len(commit.Parents) {
+				return "", fmt.Errorf("unknown revision %q: commit %s has no parent %d", rev, hash[:8], n)
+			}
+			hash = commit.Parents[n-1]
+		default:
+			n := 1
+			if m[3] != "" {
+				n, _ = strconv.Atoi(m[3])
+			}
+			for i := 0; i < n; i++ {
+				commit, err := r.commit(hash)
+				if err != nil {
+					return "", err
+				}
+				if len(commit.Parents) == 0 {
+					return "", fmt.Errorf("unknown revision %q: commit %s has no parent", rev, hash[:8])
+				}
+				hash = commit.Parents[0]
+			}
+		}
+	}
+
+	return hash, nil
+}
+
+// resolveName resolves a ref name or object name to an object hash,
+// searching refs in the same order as git
+func (r *nativeRepository) resolveName(name string) (string, error) {
+	if name == "" || name == "@" {
+		name = "HEAD"
+	}
+	if hashPattern.MatchString(name) {
+		return strings.ToLower(name), nil
+	}
+
+	candidates := []string{
+		name,
+		"refs/" + name,
+		"refs/tags/" + name,
+		"refs/heads/" + name,
+		"refs/remotes/" + name,
+		"refs/remotes/" + name + "/HEAD",
+	}
+	for _, ref := range candidates {
+		hash, err := r.readRef(ref, 0)
+		if err == nil {
+			return hash, nil
+		}
+	}
+
+	if shortHashPattern.MatchString(name) {
+		return r.objects.expand(name)
+	}
+	return "", errObjectNotFound
+}
+
+// readRef reads a loose or packed ref, following symbolic refs
+func (r *nativeRepository) readRef(ref string, depth int) (string, error) {
+	if depth > 5 {
+		return "", fmt.Errorf("symbolic ref loop at %s", ref)
+	}
+
+	// Pseudo refs like HEAD are per worktree, everything else is shared
+	dirs := []string{r.commonDir}
+	if !strings.HasPrefix(ref, "refs/") || strings.HasPrefix(ref, "refs/bisect/") {
+		dirs = []string{r.gitDir}
+	}
+	for _, dir := range dirs {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref)))
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(content))
+		if target, ok := strings.CutPrefix(value, "ref: "); ok {
+			return r.readRef(target, depth+1)
+		}
+		if hashPattern.MatchString(value) {
+			return strings.ToLower(value), nil
+		}
+	}
+
+	return r.readPackedRef(ref)
+}
+
+func (r *nativeRepository) readPackedRef(ref string) (string, error) {
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		return "", errObjectNotFound
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		hash, name, ok := strings.Cut(line, " ")
+		if ok && name == ref {
+			if !hashPattern.MatchString(hash) {
+				return "", fmt.Errorf("malformed packed ref %s", ref)
+			}
+			return strings.ToLower(hash), nil
+		}
+	}
+	return "", errObjectNotFound
+}
+
+// listRefs returns the hashes of the loose and packed refs whose names
+// start with prefix, keyed by ref name. Loose refs win over packed ones.
+func (r *nativeRepository) listRefs(prefix string) (map[string]string, error) {
+	refs := make(map[string]string)
+
+	if f, err := os.Open(filepath.Join(r.commonDir, "packed-refs")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+				continue
+			}
+			hash, name, ok := strings.Cut(line, " ")
+			if ok && strings.HasPrefix(name, prefix) && hashPattern.MatchString(hash) {
+				refs[name] = strings.ToLower(hash)
+			}
+		}
+		err := scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Walk from the directory holding the prefix, which may end mid-name
+	dir := prefix[:strings.LastIndex(prefix, "/")+1]
+	root := filepath.Join(r.commonDir, filepath.FromSlash(dir))
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(r.commonDir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ".lock") {
+			return nil
+		}
+		if hash, err := r.readRef(name, 0); err == nil {
+			refs[name] = hash
+		}
+		return nil
+	})
+	return refs, err
+}
+
+// peelToCommit dereferences annotated tags until it reaches a commit
+func (r *nativeRepository) peelToCommit(hash string) (string, error) {
+	for i := 0; i < 10; i++ {
+		objType, data, err := r.objects.read(hash)
+		if err != nil {
+			return "", err
+		}
+		switch objType {
+		case "commit":
+			return hash, nil
+		case "tag":
+			header, _, _ := strings.Cut(string(data), "\n")
+			target, ok := strings.CutPrefix(header, "object ")
+			if !ok {
+				return "", fmt.Errorf("malformed tag %s", hash)
+			}
+			hash = target
+		default:
+			return "", fmt.Errorf("object %s is a %s, not a commit", hash, objType)
+		}
+	}
+	return "", fmt.Errorf("tag chain too deep at %s", hash)
+}
+
+// commitQueue orders commits by committer date, newest first, keeping
+// insertion order for commits with the same date
+type commitQueue struct {
+	items []*Commit
+	order []int
+	next  int
+}
+
+func (q *commitQueue) Len() int { return len(q.items) }
+
+func (q *commitQueue) Less(i, j int) bool {
+	di, dj := q.items[i].Committer.Date, q.items[j].Committer.Date
+	if !di.Equal(dj) {
+		return di.After(dj)
+	}
+	return q.order[i] < q.order[j]
+}
+
+func (q *commitQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.order[i], q.order[j] = q.order[j], q.order[i]
+}
+
+func (q *commitQueue) Push(x interface{}) {
+	q.items = append(q.items, x.(*Commit))
+	q.order = append(q.order, q.next)
+	q.next++
+}
+
+func (q *commitQueue) Pop() interface{} {
+	n := len(q.items) - 1
+	item := q.items[n]
+	q.items = q.items[:n]
+	q.order = q.order[:n]
+	return item
+}
@@ -0,0 +1,375 @@
+package git
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The fixture repositories are created by testdata/generate.sh
+var fixtureRepos = []string{"testdata/loose.git", "testdata/packed.git"}
+
+func TestNativeRepository_Commits(t *testing.T) {
+	tests := []struct {
+		commitRange string
+		want        []string
+	}{
+		{
+			commitRange: "main",
+			want: []string{
+				"refactor: final change",
+				"Merge branch 'feature'",
+				"docs: tweak line 10",
+				"feat(feature): add feature file",
+				"fix(notes): correct line 200",
+				"feat: initial import",
+			},
+		},
+		{
+			commitRange: "v1.0..main",
+			want: []string{
+				"refactor: final change",
+				"Merge branch 'feature'",
+				"docs: tweak line 10",
+				"feat(feature): add feature file",
+			},
+		},
+		{
+			commitRange: "HEAD~2..HEAD",
+			want: []string{
+				"refactor: final change",
+				"Merge branch 'feature'",
+				"feat(feature): add feature file",
+			},
+		},
+		{
+			commitRange: "HEAD^^2",
+			want: []string{
+				"feat(feature): add feature file",
+				"fix(notes): correct line 200",
+				"feat: initial import",
+			},
+		},
+		{
+			commitRange: "28e0d3e..refs/heads/feature",
+			want:        []string{"feat(feature): add feature file"},
+		},
+		{
+			commitRange: "main..feature",
+			want:        []string{},
+		},
+	}
+
+	for _, fixture := range fixtureRepos {
+		repo, err := openNativeAt(fixture)
+		if err != nil {
+			t.Fatalf("openNativeAt(%s) error = %v", fixture, err)
+		}
+		defer repo.Close()
+
+		for _, tt := range tests {
+			t.Run(filepath.Base(fixture)+"/"+tt.commitRange, func(t *testing.T) {
+				commits, err := repo.Commits(tt.commitRange)
+				if err != nil {
+					t.Fatalf("Commits() error = %v", err)
+				}
+				got := []string{}
+				for _, c := range commits {
+					got = append(got, c.Subject)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Commits(%q) = %q, want %q", tt.commitRange, got, tt.want)
+				}
+			})
+		}
+
+		t.Run(filepath.Base(fixture)+"/metadata", func(t *testing.T) {
+			commits, err := repo.Commits("HEAD~2..HEAD~1^2")
+			if err != nil {
+				t.Fatalf("Commits() error = %v", err)
+			}
+			if len(commits) != 1 {
+				t.Fatalf("Expected 1 commit, got %d", len(commits))
+			}
+			c := commits[0]
+			if c.Message != "feat(feature): add feature file\n\nUsage:\n---\nfront: matter\n---" {
+				t.Errorf("Unexpected message %q", c.Message)
+			}
+			if c.Author.Name != "Ada Lovelace" || c.Author.Email != "ada@example.com" {
+				t.Errorf("Unexpected author %+v", c.Author)
+			}
+			if _, offset := c.Committer.Date.Zone(); offset != 3600 || c.Committer.Date.Hour() != 10 {
+				t.Errorf("Unexpected committer date %v", c.Committer.Date)
+			}
+
+			merges, err := repo.Commits("HEAD~1^..HEAD~1")
+			if err != nil || len(merges) == 0 || len(merges[0].Parents) != 2 {
+				t.Errorf("Expected the merge commit to have two parents, got %+v (%v)", merges, err)
+			}
+
+			branch, err := repo.CurrentBranch()
+			if err != nil || branch != "main" {
+				t.Errorf("CurrentBranch() = %q, %v", branch, err)
+			}
+		})
+
+		// Commits hidden by the range are read but never cached
+		if _, err := repo.Commits("v1.0..main"); err != nil || len(repo.commits) != 0 {
+			t.Errorf("%s: %d commits left cached (%v)", fixture, len(repo.commits), err)
+		}
+
+		if _, err := repo.Commits("nope..main"); err == nil {
+			t.Errorf("%s: expected an error for an unknown revision", fixture)
+		}
+	}
+}
+
+// TestObjectStore_ReadAll reads every object in the fixtures, including
+// deltified ones, and checks that its content hashes back to its name
+func TestObjectStore_ReadAll(t *testing.T) {
+	for _, fixture := range fixtureRepos {
+		store, err := openObjectStore(filepath.Join(fixture, "objects"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.close()
+
+		var names []string
+		for _, p := range store.packs {
+			for i := 0; i < int(p.fanout[255]); i++ {
+				names = append(names, hex.EncodeToString(p.name(i)))
+			}
+		}
+		loose, _ := filepath.Glob(filepath.Join(fixture, "objects", "??", "*"))
+		for _, path := range loose {
+			names = append(names, filepath.Base(filepath.Dir(path))+filepath.Base(path))
+		}
+		if len(names) == 0 {
+			t.Fatalf("%s: no objects found", fixture)
+		}
+
+		for _, name := range names {
+			objType, data, err := store.read(name)
+			if err != nil {
+				t.Fatalf("%s: read(%s) error = %v", fixture, name, err)
+			}
+			sum := sha1.Sum(append([]byte(fmt.Sprintf("%s %d\x00", objType, len(data))), data...))
+			if hex.EncodeToString(sum[:]) != name {
+				t.Errorf("%s: object %s has wrong content", fixture, name)
+			}
+		}
+	}
+}
+
+// TestNativeRepository_MatchesExec compares both backends on a live
+// repository, read from a linked worktree
+func TestNativeRepository_MatchesExec(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("feat: first")
+	repo.commit("fix: second\n\nbody")
+	repo.git("gc", "-q")
+	repo.commit("docs: third")
+
+	worktree := filepath.Join(t.TempDir(), "wt")
+	repo.git("worktree", "add", "-q", "-b", "topic", worktree)
+	if err := os.Chdir(worktree); err != nil {
+		t.Fatal(err)
+	}
+	repo.dir = worktree
+	repo.commit("test: in worktree")
+
+	native, err := Open(BackendNative)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer native.Close()
+
+	for _, commitRange := range []string{"HEAD", "HEAD~2..HEAD", "main..topic"} {
+		want, err := GetCommits(commitRange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := native.Commits(commitRange)
+		if err != nil {
+			t.Fatalf("Commits(%q) error = %v", commitRange, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Commits(%q) returned %d commits, want %d", commitRange, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].Hash != want[i].Hash || got[i].Message != want[i].Message ||
+				strings.Join(got[i].Parents, " ") != strings.Join(want[i].Parents, " ") ||
+				!got[i].Committer.Date.Equal(want[i].Committer.Date) {
+				t.Errorf("Commits(%q)[%d] = %+v, want %+v", commitRange, i, got[i], want[i])
+			}
+		}
+	}
+
+	wantDir, _ := CommonDir()
+	if dir, err := native.CommonDir(); err != nil || dir != wantDir {
+		t.Errorf("CommonDir() = %q, %v, want %q", dir, err, wantDir)
+	}
+
+	if branch, _ := native.CurrentBranch(); branch != "topic" {
+		t.Errorf("CurrentBranch() = %q, want topic", branch)
+	}
+}
+
+// TestNativeRepository_ChangedFiles compares the tree diff with git
+// diff-tree on renames, deletions, nested directories, a directory
+// replaced by a file and a merge
+func TestNativeRepository_ChangedFiles(t *testing.T) {
+	repo := newTestRepo(t)
+	write := func(files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			path := filepath.Join(repo.dir, filepath.FromSlash(name))
+			if content == "" {
+				if err := os.RemoveAll(path); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		repo.git("add", "-A")
+	}
+
+	write(map[string]string{"README.md": "hi\n", "src/a/b.go": "package a\n", "src/c.go": "package src\n"})
+	repo.commit("feat: first")
+	write(map[string]string{"src/a/b.go": "package a // changed\n", "docs/guide.md": "guide\n"})
+	repo.commit("feat: second")
+	write(map[string]string{"src/a": "", "src/a/b.go": "", "README.md": ""})
+	write(map[string]string{"src/a": "now a file\n", "src/c.go": "", "src/d.go": "package src\n"})
+	repo.commit("refactor: third")
+	repo.git("checkout", "-q", "-b", "topic", "HEAD~1")
+	write(map[string]string{"topic.txt": "topic\n"})
+	repo.commit("feat: topic")
+	repo.git("checkout", "-q", "main")
+	repo.git("merge", "-q", "--no-ff", "-m", "Merge branch 'topic'", "topic")
+	repo.git("gc", "-q")
+
+	native, err := Open(BackendNative)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer native.Close()
+
+	hashes := strings.Fields(repo.git("rev-list", "--all"))
+	if len(hashes) != 5 {
+		t.Fatalf("Expected 5 commits, got %d", len(hashes))
+	}
+	for _, hash := range hashes {
+		want, err := ChangedFiles(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := native.ChangedFiles(hash)
+		if err != nil {
+			t.Fatalf("ChangedFiles(%s) error = %v", hash, err)
+		}
+		sort.Strings(want)
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("ChangedFiles(%s) = %q, want %q", hash, got, want)
+		}
+	}
+}
+
+// TestNativeRepository_Quarantine reads a commit that only exists in the
+// quarantine directory git sets up for pre-receive hooks
+func TestNativeRepository_Quarantine(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("feat: first")
+
+	quarantine := filepath.Join(repo.dir, ".git", "objects", "incoming-test")
+	if err := os.MkdirAll(quarantine, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("git", "commit-tree", "HEAD^{tree}", "-p", "HEAD", "-m", "feat: pushed")
+	cmd.Dir = repo.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_OBJECT_DIRECTORY="+quarantine,
+		"GIT_ALTERNATE_OBJECT_DIRECTORIES="+filepath.Join(repo.dir, ".git", "objects"))
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git commit-tree: %v", err)
+	}
+	pushed := strings.TrimSpace(string(output))
+
+	native, err := Open(BackendNative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = native.Commits(pushed)
+	native.Close()
+	if err == nil {
+		t.Fatal("Expected the quarantined commit to be missing without the environment")
+	}
+
+	t.Setenv("GIT_OBJECT_DIRECTORY", quarantine)
+	t.Setenv("GIT_ALTERNATE_OBJECT_DIRECTORIES", filepath.Join(repo.dir, ".git", "objects"))
+	native, err = Open(BackendNative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer native.Close()
+
+	commits, err := native.Commits(pushed)
+	if err != nil {
+		t.Fatalf("Commits() error = %v", err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "feat: pushed" || commits[1].Subject != "feat: first" {
+		t.Errorf("Unexpected commits %+v", commits)
+	}
+}
+
+// TestNativeRepository_MalformedRefs checks that refs holding something
+// other than a full hash fail instead of being used as object names
+func TestNativeRepository_MalformedRefs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"HEAD":            "ref: refs/heads/main\n",
+		"refs/heads/main": "a\n",
+		"packed-refs":     "# pack-refs with: peeled\nb refs/heads/packed\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := openNativeAt(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	for _, rev := range []string{"main", "packed", "refs/heads/packed"} {
+		if hash, err := repo.ResolveRevision(rev); err == nil {
+			t.Errorf("ResolveRevision(%q) = %q, want an error", rev, hash)
+		}
+	}
+	if _, _, err := repo.objects.read("a"); err == nil {
+		t.Error("Expected an error for a short object name")
+	}
+}
@@ -0,0 +1,452 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Object types as stored in packfiles
+const (
+	objCommit   = 1
+	objTree     = 2
+	objBlob     = 3
+	objTag      = 4
+	objOfsDelta = 6
+	objRefDelta = 7
+)
+
+var objectTypeNames = map[int]string{
+	objCommit: "commit",
+	objTree:   "tree",
+	objBlob:   "blob",
+	objTag:    "tag",
+}
+
+// errObjectNotFound is returned when no object store contains a hash
+var errObjectNotFound = errors.New("object not found")
+
+// objectStore reads objects from the loose object directories and
+// packfiles of a repository, including its alternates
+type objectStore struct {
+	dirs  []string
+	packs []*packFile
+}
+
+// openObjectStore opens objectsDir, then the extra alternates such as
+// those in $GIT_ALTERNATE_OBJECT_DIRECTORIES
+func openObjectStore(objectsDir string, alternates []string) (*objectStore, error) {
+	store := &objectStore{}
+	seen := make(map[string]bool)
+	for _, dir := range append([]string{objectsDir}, alternates...) {
+		if dir == "" {
+			continue
+		}
+		if err := store.addDir(dir, seen); err != nil {
+			// Release the packs opened before the failure
+			store.close()
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// addDir registers an objects directory, its packs and its alternates
+func (s *objectStore) addDir(dir string, seen map[string]bool) error {
+	dir = filepath.Clean(dir)
+	if seen[dir] {
+		return nil
+	}
+	seen[dir] = true
+	s.dirs = append(s.dirs, dir)
+
+	indexes, err := filepath.Glob(filepath.Join(dir, "pack", "*.idx"))
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		pack, err := openPack(idx)
+		if err != nil {
+			return err
+		}
+		s.packs = append(s.packs, pack)
+	}
+
+	alternates, err := os.ReadFile(filepath.Join(dir, "info", "alternates"))
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(alternates), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(dir, line)
+		}
+		if err := s.addDir(line, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close releases the packfiles held open by the store
+func (s *objectStore) close() error {
+	var first error
+	for _, p := range s.packs {
+		if err := p.close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	s.packs = nil
+	return first
+}
+
+// read returns the type and content of an object
+func (s *objectStore) read(hash string) (string, []byte, error) {
+	if !hashPattern.MatchString(hash) {
+		return "", nil, fmt.Errorf("invalid object name %q", hash)
+	}
+	hash = strings.ToLower(hash)
+	for _, dir := range s.dirs {
+		objType, data, err := readLooseObject(filepath.Join(dir, hash[:2], hash[2:]))
+		if err == nil {
+			return objType, data, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("reading object %s: %w", hash, err)
+		}
+	}
+
+	raw, _ := hex.DecodeString(hash)
+	for _, p := range s.packs {
+		if offset, ok := p.find(raw); ok {
+			typ, data, err := p.readAt(offset, s)
+			if err != nil {
+				return "", nil, fmt.Errorf("reading object %s: %w", hash, err)
+			}
+			return objectTypeNames[typ], data, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("%w: %s", errObjectNotFound, hash)
+}
+
+// expand resolves an abbreviated object name to the full hash
+func (s *objectStore) expand(prefix string) (string, error) {
+	prefix = strings.ToLower(prefix)
+	matches := make(map[string]bool)
+
+	for _, dir := range s.dirs {
+		entries, err := os.ReadDir(filepath.Join(dir, prefix[:2]))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if name := prefix[:2] + e.Name(); len(name) == 40 && strings.HasPrefix(name, prefix) {
+				matches[name] = true
+			}
+		}
+	}
+	for _, p := range s.packs {
+		for _, name := range p.withPrefix(prefix) {
+			matches[name] = true
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", errObjectNotFound, prefix)
+	case 1:
+		for name := range matches {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("short object name %s is ambiguous", prefix)
+}
+
+func readLooseObject(path string) (string, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	header, data, ok := bytes.Cut(content, []byte{0})
+	if !ok {
+		return "", nil, fmt.Errorf("malformed loose object %s", path)
+	}
+	objType, size, _ := strings.Cut(string(header), " ")
+	if n, err := strconv.Atoi(size); err != nil || n != len(data) {
+		return "", nil, fmt.Errorf("malformed loose object %s", path)
+	}
+	return objType, data, nil
+}
+
+// packFile is a packfile together with its version 2 index
+type packFile struct {
+	pack    *os.File
+	fanout  [256]uint32
+	names   []byte
+	offsets []byte
+	large   []byte
+}
+
+func openPack(idxPath string) (*packFile, error) {
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index %s", idxPath)
+	}
+
+	p := &packFile{}
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(idx[8+i*4:])
+	}
+	n := int(p.fanout[255])
+	pos := 8 + 256*4
+	if len(idx) < pos+n*(20+4+4) {
+		return nil, fmt.Errorf("truncated pack index %s", idxPath)
+	}
+	p.names = idx[pos : pos+n*20]
+	pos += n * 20
+	pos += n * 4 // CRC32 values
+	p.offsets = idx[pos : pos+n*4]
+	p.large = idx[pos+n*4:]
+
+	p.pack, err = os.Open(strings.TrimSuffix(idxPath, ".idx") + ".pack")
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *packFile) close() error {
+	return p.pack.Close()
+}
+
+func (p *packFile) name(i int) []byte {
+	return p.names[i*20 : i*20+20]
+}
+
+// find returns the pack offset of an object
+func (p *packFile) find(hash []byte) (int64, bool) {
+	lo, hi := 0, int(p.fanout[hash[0]])
+	if hash[0] > 0 {
+		lo = int(p.fanout[hash[0]-1])
+	}
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.name(lo+i), hash) >= 0
+	})
+	if i >= hi || !bytes.Equal(p.name(i), hash) {
+		return 0, false
+	}
+
+	offset := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if offset&0x80000000 != 0 {
+		j := int(offset & 0x7fffffff)
+		return int64(binary.BigEndian.Uint64(p.large[j*8:])), true
+	}
+	return int64(offset), true
+}
+
+// withPrefix lists the objects whose hex name starts with prefix
+func (p *packFile) withPrefix(prefix string) []string {
+	first, err := strconv.ParseUint(prefix[:2], 16, 8)
+	if err != nil {
+		return nil
+	}
+	lo, hi := 0, int(p.fanout[first])
+	if first > 0 {
+		lo = int(p.fanout[first-1])
+	}
+
+	var names []string
+	for i := lo; i < hi; i++ {
+		if name := hex.EncodeToString(p.name(i)); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// readAt reads the object stored at offset, resolving deltas against their
+// base objects
+func (p *packFile) readAt(offset int64, store *objectStore) (int, []byte, error) {
+	r := bufio.NewReader(io.NewSectionReader(p.pack, offset, 1<<62))
+
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	typ := int(b>>4) & 7
+	size := int64(b & 0x0f)
+	for shift := 4; b&0x80 != 0; shift += 7 {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+		size |= int64(b&0x7f) << shift
+	}
+
+	var baseType int
+	var base []byte
+	switch typ {
+	case objOfsDelta:
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		distance := int64(b & 0x7f)
+		for b&0x80 != 0 {
+			if b, err = r.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			distance = ((distance + 1) << 7) | int64(b&0x7f)
+		}
+		if baseType, base, err = p.readAt(offset-distance, store); err != nil {
+			return 0, nil, err
+		}
+	case objRefDelta:
+		hash := make([]byte, 20)
+		if _, err := io.ReadFull(r, hash); err != nil {
+			return 0, nil, err
+		}
+		var typeName string
+		if typeName, base, err = store.read(hex.EncodeToString(hash)); err != nil {
+			return 0, nil, err
+		}
+		for t, name := range objectTypeNames {
+			if name == typeName {
+				baseType = t
+			}
+		}
+	case objCommit, objTree, objBlob, objTag:
+	default:
+		return 0, nil, fmt.Errorf("unknown object type %d at offset %d", typ, offset)
+	}
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if int64(len(data)) != size {
+		return 0, nil, fmt.Errorf("object at offset %d has size %d, expected %d", offset, len(data), size)
+	}
+
+	if base == nil {
+		return typ, data, nil
+	}
+	result, err := applyDelta(base, data)
+	return baseType, result, err
+}
+
+// applyDelta rebuilds an object from its base and a git delta
+func applyDelta(base, delta []byte) ([]byte, error) {
+	readSize := func() (int, error) {
+		size, shift := 0, 0
+		for {
+			if len(delta) == 0 {
+				return 0, errors.New("truncated delta header")
+			}
+			b := delta[0]
+			delta = delta[1:]
+			size |= int(b&0x7f) << shift
+			shift += 7
+			if b&0x80 == 0 {
+				return size, nil
+			}
+		}
+	}
+
+	baseSize, err := readSize()
+	if err != nil {
+		return nil, err
+	}
+	if baseSize != len(base) {
+		return nil, fmt.Errorf("delta base has size %d, expected %d", len(base), baseSize)
+	}
+	resultSize, err := readSize()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, 0, resultSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+
+		switch {
+		case op&0x80 != 0:
+			// Copy a range of the base object
+			var offset, size int
+			for i := 0; i < 4; i++ {
+				if op&(1<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errors.New("truncated delta copy")
+					}
+					offset |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if op&(0x10<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errors.New("truncated delta copy")
+					}
+					size |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, errors.New("delta copy out of range")
+			}
+			result = append(result, base[offset:offset+size]...)
+		case op != 0:
+			// Insert literal data
+			if int(op) > len(delta) {
+				return nil, errors.New("truncated delta insert")
+			}
+			result = append(result, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errors.New("invalid delta opcode 0")
+		}
+	}
+
+	if len(result) != resultSize {
+		return nil, fmt.Errorf("delta produced %d bytes, expected %d", len(result), resultSize)
+	}
+	return result, nil
+}
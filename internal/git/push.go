@@ -0,0 +1,91 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// zeroHash is what git passes for the missing side of a ref creation or
+// deletion
+const zeroHash = "0000000000000000000000000000000000000000"
+
+// RefUpdate is one ref being changed by a push
+type RefUpdate struct {
+	Ref string
+	Old string
+	New string
+}
+
+// IsDelete reports whether the update removes the ref
+func (u RefUpdate) IsDelete() bool {
+	return isZeroHash(u.New)
+}
+
+// IsCreate reports whether the update creates the ref
+func (u RefUpdate) IsCreate() bool {
+	return isZeroHash(u.Old)
+}
+
+// Ref name prefixes that hold the commits a receiver already has, for
+// RefUpdate.Revisions
+const (
+	// RemoteRefs is what a clone knows its remotes have
+	RemoteRefs = "refs/remotes/"
+	// AllRefs is everything the receiving repository has
+	AllRefs = "refs/"
+)
+
+// Revisions returns the commits the update adds. A new ref has no old value
+// to compare with, so its commits are those not reachable from any ref
+// starting with existingRefs: RemoteRefs for a push from a clone, AllRefs
+// on the receiving side. A deletion adds nothing and has no Include.
+func (u RefUpdate) Revisions(existingRefs string) Revisions {
+	switch {
+	case u.IsDelete():
+		return Revisions{}
+	case u.IsCreate():
+		return Revisions{Include: u.New, ExcludeRefs: []string{existingRefs}}
+	default:
+		return Revisions{Include: u.New, Exclude: []string{u.Old}}
+	}
+}
+
+func isZeroHash(hash string) bool {
+	return strings.Trim(hash, "0") == ""
+}
+
+// ParsePrePush reads the ref updates git passes to a pre-push hook, one per
+// line as "<local ref> <local hash> <remote ref> <remote hash>". The
+// returned updates are named after the remote ref.
+func ParsePrePush(r io.Reader) ([]RefUpdate, error) {
+	return parseRefUpdates(r, func(fields []string) RefUpdate {
+		return RefUpdate{Ref: fields[2], Old: fields[3], New: fields[1]}
+	}, 4)
+}
+
+// ParsePreReceive reads the ref updates git passes to a pre-receive hook,
+// one per line as "<old hash> <new hash> <ref>"
+func ParsePreReceive(r io.Reader) ([]RefUpdate, error) {
+	return parseRefUpdates(r, func(fields []string) RefUpdate {
+		return RefUpdate{Ref: fields[2], Old: fields[0], New: fields[1]}
+	}, 3)
+}
+
+func parseRefUpdates(r io.Reader, parse func([]string) RefUpdate, n int) ([]RefUpdate, error) {
+	var updates []RefUpdate
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != n {
+			return nil, fmt.Errorf("malformed ref update %q", line)
+		}
+		updates = append(updates, parse(fields))
+	}
+	return updates, scanner.Err()
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+)
+
+// defaultBranches are tried in order when the checked out branch has no
+// upstream, to find where it branched off
+var defaultBranches = []string{"origin/HEAD", "origin/main", "origin/master"}
+
+// RangeChoice is a commit range picked for the user and why it was picked
+type RangeChoice struct {
+	Range  string
+	Reason string
+}
+
+// DefaultRange picks the commits worth linting when no range is given:
+// those not yet on the upstream branch, or on the remote's default branch
+// when there is no upstream. Unless sinceUpstream is set, it falls back to
+// the last commit when neither exists.
+//
+// Example usage:
+//
+//	choice, err := git.DefaultRange(repo, false)
+//	if err != nil {
+//	   return err
+//	}
+//	fmt.Printf("Checking %s: %s\n", choice.Range, choice.Reason)
+func DefaultRange(repo Repository, sinceUpstream bool) (RangeChoice, error) {
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return RangeChoice{}, errors.New("the repository has no commits yet")
+	}
+
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		return RangeChoice{}, err
+	}
+	if branch != "" {
+		upstream, err := repo.Upstream()
+		if err != nil {
+			return RangeChoice{}, err
+		}
+		if upstream != "" {
+			return RangeChoice{
+				Range:  upstream + "..HEAD",
+				Reason: fmt.Sprintf("commits on %s not yet on %s", branch, upstream),
+			}, nil
+		}
+	}
+
+	shallow, err := repo.IsShallow()
+	if err != nil {
+		return RangeChoice{}, err
+	}
+
+	for _, remote := range defaultBranches {
+		if _, err := repo.ResolveRevision(remote); err != nil {
+			continue
+		}
+		base, err := repo.MergeBase(remote, "HEAD")
+		if err != nil {
+			if shallow {
+				return shallowFallback(repo, sinceUpstream, fmt.Sprintf(
+					"the shallow clone has no history in common with %s", remote))
+			}
+			break
+		}
+
+		switch {
+		case base == head:
+			return RangeChoice{
+				Range:  "HEAD..HEAD",
+				Reason: fmt.Sprintf("HEAD is already on %s", remote),
+			}, nil
+		case branch == "":
+			return RangeChoice{
+				Range:  base + "..HEAD",
+				Reason: fmt.Sprintf("HEAD is detached; commits since it branched off %s at %s", remote, base[:8]),
+			}, nil
+		default:
+			return RangeChoice{
+				Range:  base + "..HEAD",
+				Reason: fmt.Sprintf("%s has no upstream; commits since it branched off %s at %s", branch, remote, base[:8]),
+			}, nil
+		}
+	}
+
+	if sinceUpstream {
+		return RangeChoice{}, errors.New("found neither an upstream branch nor origin/main to compare with")
+	}
+	if _, err := repo.ResolveRevision("HEAD^"); err != nil {
+		if shallow {
+			return shallowFallback(repo, false, "the parent of HEAD is not in the shallow clone")
+		}
+		return RangeChoice{Range: "HEAD", Reason: "HEAD is the first commit"}, nil
+	}
+	return RangeChoice{Range: "HEAD^..HEAD", Reason: "no upstream branch found; checking the last commit only"}, nil
+}
+
+// shallowFallback checks the last commit of a shallow clone, whose parent
+// may be missing, and suggests fetching more history
+func shallowFallback(repo Repository, sinceUpstream bool, why string) (RangeChoice, error) {
+	hint := "run \"git fetch --unshallow\" to check more"
+	if sinceUpstream {
+		return RangeChoice{}, fmt.Errorf("%s; %s", why, hint)
+	}
+
+	commitRange := "HEAD^..HEAD"
+	if _, err := repo.ResolveRevision("HEAD^"); err != nil {
+		commitRange = "HEAD"
+	}
+	return RangeChoice{
+		Range:  commitRange,
+		Reason: fmt.Sprintf("%s; checking the last commit only (%s)", why, hint),
+	}, nil
+}
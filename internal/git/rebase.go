@@ -0,0 +1,188 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RewordPlanEnv names the environment variable through which Reword hands
+// the plan directory to the sequence editor
+const RewordPlanEnv = "GIT_COMMIT_LINTER_REWORD_PLAN"
+
+// rewordEditFile marks a plan whose messages are opened in the editor
+const rewordEditFile = "edit"
+
+// pickPattern matches the pick lines of a rebase todo list
+var pickPattern = regexp.MustCompile(`^(?:p|pick) ([0-9a-f]+)\b`)
+
+// IsPublished reports whether a commit is reachable from a remote-tracking
+// branch, so rewriting it would rewrite history others may have
+func IsPublished(hash string) (bool, error) {
+	output, err := exec.Command("git", "for-each-ref", "--count=1", "--contains", hash, "refs/remotes").Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether %s was pushed: %w", hash, err)
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// IsAncestor reports whether commit a is an ancestor of commit b, or the
+// same commit
+func IsAncestor(a, b string) (bool, error) {
+	err := exec.Command("git", "merge-base", "--is-ancestor", a, b).Run()
+	if err == nil {
+		return true, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, err
+}
+
+// IsWorkTreeClean reports whether the work tree and index match HEAD,
+// ignoring untracked files
+func IsWorkTreeClean() (bool, error) {
+	output, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+	if err != nil {
+		return false, err
+	}
+	return len(output) == 0, nil
+}
+
+// HasMerges reports whether there are merge commits between base and
+// HEAD, or in all of HEAD's history when base is empty
+func HasMerges(base string) (bool, error) {
+	revs := "HEAD"
+	if base != "" {
+		revs = base + "..HEAD"
+	}
+	output, err := exec.Command("git", "rev-list", "--merges", "--count", revs).Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to list the commits since %s: %w", shortHash(base), err)
+	}
+	return strings.TrimSpace(string(output)) != "0", nil
+}
+
+// Reword rewrites the messages of commits on the current branch with a
+// non-interactive rebase. messages maps full commit hashes to their new
+// messages; with edit set, each is opened in the editor first. base is the
+// commit to rebase onto, or an empty string to rebase from the root.
+// sequenceEditor is the command and arguments of a program that calls
+// RewordTodo on the todo file git appends to them.
+//
+// History with merges is refused, since replaying a merge can stop on the
+// conflicts resolved in it. Commit hooks are skipped, since only messages
+// change.
+func Reword(base string, messages map[string]string, edit bool, sequenceEditor []string) error {
+	merges, err := HasMerges(base)
+	if err != nil {
+		return err
+	}
+	if merges {
+		return fmt.Errorf("the commits since %s include merges, which a rebase would flatten; reword them by hand", shortHash(base))
+	}
+
+	dir, err := os.MkdirTemp("", "git-commit-linter-reword-")
+	if err != nil {
+		return err
+	}
+
+	for hash, message := range messages {
+		// The messages are committed verbatim, so they need the final
+		// newline git commit would add
+		message = strings.TrimRight(message, "\n") + "\n"
+		if err := os.WriteFile(filepath.Join(dir, hash), []byte(message), 0644); err != nil {
+			return err
+		}
+	}
+	if edit {
+		if err := os.WriteFile(filepath.Join(dir, rewordEditFile), nil, 0644); err != nil {
+			return err
+		}
+	}
+
+	args := []string{"rebase", "--interactive", "--no-autosquash"}
+	if base == "" {
+		args = append(args, "--root")
+	} else {
+		args = append(args, base)
+	}
+	editor := make([]string, len(sequenceEditor))
+	for i, arg := range sequenceEditor {
+		editor[i] = ShellQuote(arg)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_SEQUENCE_EDITOR="+strings.Join(editor, " "), RewordPlanEnv+"="+dir)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		// The todo list still refers to the messages in dir, so it is kept
+		// for "git rebase --continue"
+		return fmt.Errorf("git rebase failed: %w; run \"git rebase --abort\" to return to where you started", err)
+	}
+	return os.RemoveAll(dir)
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "the root commit"
+	}
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
+
+// RewordTodo is the sequence editor Reword runs. It adds an exec line that
+// amends the message after each commit in the plan directory named by
+// RewordPlanEnv.
+func RewordTodo(todoPath string) error {
+	dir := os.Getenv(RewordPlanEnv)
+	if dir == "" {
+		return fmt.Errorf("%s is not set; this command is run by fix-history", RewordPlanEnv)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	_, statErr := os.Stat(filepath.Join(dir, rewordEditFile))
+	edit := statErr == nil
+
+	content, err := os.ReadFile(todoPath)
+	if err != nil {
+		return err
+	}
+
+	var todo strings.Builder
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		todo.WriteString(line)
+		m := pickPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Name() == rewordEditFile || !strings.HasPrefix(entry.Name(), m[1]) {
+				continue
+			}
+			if !strings.HasSuffix(line, "\n") {
+				todo.WriteString("\n")
+			}
+			todo.WriteString(amendCommand(filepath.Join(dir, entry.Name()), edit) + "\n")
+		}
+	}
+	return os.WriteFile(todoPath, []byte(todo.String()), 0644)
+}
+
+// amendCommand returns the todo line that replaces the message of the
+// commit just picked with the one in messagePath
+func amendCommand(messagePath string, edit bool) string {
+	command := "exec git commit --amend --allow-empty --no-verify --quiet"
+	if edit {
+		command += " --edit"
+	} else {
+		command += " --cleanup=verbatim"
+	}
+	return command + " --file " + ShellQuote(messagePath)
+}
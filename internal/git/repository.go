@@ -0,0 +1,141 @@
+package git
+
+import (
+	"context"
+	"fmt"
+)
+
+// Backends that can be passed to Open
+const (
+	BackendExec   = "exec"
+	BackendNative = "native"
+)
+
+// Repository reads commits from a git repository
+type Repository interface {
+	// Commits returns the commits in a range such as "HEAD~5..HEAD",
+	// newest first
+	Commits(commitRange string) ([]Commit, error)
+	// StreamCommits calls fn for each commit in the range as it is read,
+	// newest first, stopping early when fn fails or ctx is cancelled
+	StreamCommits(ctx context.Context, commitRange string, fn func(Commit) error) error
+	// StreamRevisions is StreamCommits for commits selected by
+	// reachability, as for a branch that is being created
+	StreamRevisions(ctx context.Context, revs Revisions, fn func(Commit) error) error
+	// CurrentBranch returns the checked out branch, or an empty string when
+	// HEAD is detached
+	CurrentBranch() (string, error)
+	// CommonDir returns the absolute path of the git directory shared by all
+	// worktrees, where tools may keep their own state
+	CommonDir() (string, error)
+	// ResolveRevision returns the hash of the commit a revision such as
+	// "HEAD^" or "origin/main" names
+	ResolveRevision(rev string) (string, error)
+	// Upstream returns the upstream of the checked out branch, such as
+	// "origin/main", or an empty string when it has none
+	Upstream() (string, error)
+	// MergeBase returns the best common ancestor of two commits
+	MergeBase(a, b string) (string, error)
+	// IsShallow reports whether the repository is a shallow clone
+	IsShallow() (bool, error)
+	// ChangedFiles returns the paths a commit changes compared with its
+	// parent, relative to the repository root. Merge commits change none.
+	ChangedFiles(hash string) ([]string, error)
+	// Close releases the files the repository holds open
+	Close() error
+}
+
+// Revisions selects the commits reachable from Include but not from any
+// of Exclude, nor from any ref whose name starts with one of ExcludeRefs.
+// It describes ranges a single "A..B" cannot, like the commits of a new
+// branch that no other ref has.
+type Revisions struct {
+	Include string
+	Exclude []string
+	// ExcludeRefs are ref name prefixes ending in "/", such as
+	// "refs/remotes/"
+	ExcludeRefs []string
+}
+
+// Open returns the repository for the current directory using the given
+// backend. The exec backend runs the git binary; the native backend reads
+// the .git directory directly and works without git installed. Only the
+// Repository methods are native: package functions such as StagedFiles,
+// ConfigValue and HooksDir always run git.
+//
+// Example usage:
+//
+//	repo, err := git.Open(git.BackendNative)
+//	if err != nil {
+//	   return err
+//	}
+//	defer repo.Close()
+//	commits, err := repo.Commits("HEAD~5..HEAD")
+func Open(backend string) (Repository, error) {
+	switch backend {
+	case BackendExec, "":
+		return ExecRepository{}, nil
+	case BackendNative:
+		return OpenNative()
+	default:
+		return nil, fmt.Errorf("unknown git backend %q (want %s or %s)", backend, BackendExec, BackendNative)
+	}
+}
+
+// ExecRepository implements Repository by running the git binary
+type ExecRepository struct{}
+
+// Commits runs git log over the range
+func (ExecRepository) Commits(commitRange string) ([]Commit, error) {
+	return GetCommits(commitRange)
+}
+
+// StreamCommits streams the output of git log
+func (ExecRepository) StreamCommits(ctx context.Context, commitRange string, fn func(Commit) error) error {
+	return StreamCommits(ctx, commitRange, fn)
+}
+
+// StreamRevisions streams the output of git log
+func (ExecRepository) StreamRevisions(ctx context.Context, revs Revisions, fn func(Commit) error) error {
+	return StreamRevisions(ctx, revs, fn)
+}
+
+// CurrentBranch runs git rev-parse
+func (ExecRepository) CurrentBranch() (string, error) {
+	return CurrentBranch()
+}
+
+// CommonDir runs git rev-parse
+func (ExecRepository) CommonDir() (string, error) {
+	return CommonDir()
+}
+
+// ResolveRevision runs git rev-parse
+func (ExecRepository) ResolveRevision(rev string) (string, error) {
+	return ResolveRevision(rev)
+}
+
+// Upstream runs git rev-parse
+func (ExecRepository) Upstream() (string, error) {
+	return Upstream()
+}
+
+// MergeBase runs git merge-base
+func (ExecRepository) MergeBase(a, b string) (string, error) {
+	return MergeBase(a, b)
+}
+
+// IsShallow runs git rev-parse
+func (ExecRepository) IsShallow() (bool, error) {
+	return IsShallow()
+}
+
+// ChangedFiles runs git diff-tree
+func (ExecRepository) ChangedFiles(hash string) ([]string, error) {
+	return ChangedFiles(hash)
+}
+
+// Close does nothing, since each command's git process exits on its own
+func (ExecRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,69 @@
+#!/bin/sh
+# Regenerates the fixture repositories used by the native backend tests.
+#
+#   loose.git   objects and refs stored loose (as after a small push)
+#   packed.git  the same history after git gc: one packfile with deltas
+#               and packed-refs
+#
+# Run from this directory: ./generate.sh
+set -e
+
+export GIT_CONFIG_GLOBAL=/dev/null GIT_CONFIG_NOSYSTEM=1
+export GIT_AUTHOR_NAME="Ada Lovelace" GIT_AUTHOR_EMAIL="ada@example.com"
+export GIT_COMMITTER_NAME="Ada Lovelace" GIT_COMMITTER_EMAIL="ada@example.com"
+
+rm -rf loose.git packed.git work
+git init -q -b main work
+cd work
+
+n=0
+commit() {
+    n=$((n + 1))
+    export GIT_AUTHOR_DATE="2024-01-01T10:00:$(printf %02d $n)+0100"
+    export GIT_COMMITTER_DATE="$GIT_AUTHOR_DATE"
+    git add -A
+    git commit -q --allow-empty -m "$1"
+}
+
+# A file large enough for git to store older versions as deltas
+seq 1 400 | sed 's/^/line /' > notes.txt
+commit "feat: initial import"
+
+sed -i 's/^line 200$/line 200 changed/' notes.txt
+commit "fix(notes): correct line 200"
+
+git tag -a v1.0 -m "release 1.0"
+
+git checkout -q -b feature
+echo "feature" > feature.txt
+commit "feat(feature): add feature file
+
+Usage:
+---
+front: matter
+---"
+
+git checkout -q main
+sed -i 's/^line 10$/line 10 changed/' notes.txt
+commit "docs: tweak line 10"
+
+export GIT_AUTHOR_DATE="2024-01-01T10:01:00+0100" GIT_COMMITTER_DATE="2024-01-01T10:01:00+0100"
+git merge -q --no-ff -m "Merge branch 'feature'" feature
+
+sed -i 's/^line 300$/line 300 changed/' notes.txt
+commit "refactor: final change"
+
+cd ..
+git init -q --bare -b main loose.git
+git -C work push -q ../loose.git main feature v1.0
+
+git clone -q --bare work packed.git
+git -C packed.git repack -q -a -d -f --window=50 --depth=50
+git -C packed.git pack-refs --all
+git -C packed.git remote remove origin
+
+for repo in loose.git packed.git; do
+    rm -rf "$repo/hooks" "$repo/info" "$repo/logs" "$repo/description"
+    rm -f "$repo"/objects/pack/*.bitmap "$repo/objects/info/packs"
+done
+rm -rf work
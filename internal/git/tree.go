@@ -0,0 +1,164 @@
+package git
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// treeMode is the mode of a subdirectory in a tree object
+const treeMode = "40000"
+
+// treeEntry is a file, subdirectory or submodule in a tree object
+type treeEntry struct {
+	mode string
+	hash string
+}
+
+// ChangedFiles compares the commit's tree with its parent's, listing files
+// like git diff-tree -r --root. The first commit lists every file; merges
+// list none, as git diff-tree shows none for them by default. Commits are
+// read without the cache, so this is safe to call during a walk.
+func (r *nativeRepository) ChangedFiles(hash string) ([]string, error) {
+	tree, parents, err := r.commitTree(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) > 1 {
+		return nil, nil
+	}
+
+	parentTree := ""
+	if len(parents) == 1 {
+		if parentTree, _, err = r.commitTree(parents[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	var files []string
+	if err := r.diffTrees("", parentTree, tree, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// commitTree returns the tree and parents a commit object records
+func (r *nativeRepository) commitTree(hash string) (string, []string, error) {
+	objType, data, err := r.objects.read(hash)
+	if err != nil {
+		return "", nil, err
+	}
+	if objType != "commit" {
+		return "", nil, fmt.Errorf("object %s is a %s, not a commit", hash, objType)
+	}
+
+	headers, _, _ := strings.Cut(string(data), "\n\n")
+	tree := ""
+	var parents []string
+	for _, line := range strings.Split(headers, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			tree = value
+		case "parent":
+			parents = append(parents, value)
+		}
+	}
+	if !hashPattern.MatchString(tree) {
+		return "", nil, fmt.Errorf("commit %s has no tree", hash)
+	}
+	if r.shallow[hash] {
+		// Like git, compare shallow commits with an empty tree
+		parents = nil
+	}
+	return tree, parents, nil
+}
+
+// diffTrees appends the paths of files that differ between two trees. An
+// empty hash stands for an empty tree. A directory replaced by a file, or
+// the reverse, lists the file and everything in the directory.
+func (r *nativeRepository) diffTrees(prefix, from, to string, files *[]string) error {
+	if from == to {
+		return nil
+	}
+	before, err := r.readTree(from)
+	if err != nil {
+		return err
+	}
+	after, err := r.readTree(to)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		old, cur := before[name], after[name]
+		if old == cur {
+			continue
+		}
+		path := prefix + name
+
+		switch {
+		case old.mode == treeMode && cur.mode == treeMode:
+			err = r.diffTrees(path+"/", old.hash, cur.hash, files)
+		case old.mode == treeMode:
+			err = r.diffTrees(path+"/", old.hash, "", files)
+			if cur.hash != "" {
+				*files = append(*files, path)
+			}
+		case cur.mode == treeMode:
+			if old.hash != "" {
+				*files = append(*files, path)
+			}
+			err = r.diffTrees(path+"/", "", cur.hash, files)
+		default:
+			*files = append(*files, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTree decodes a tree object into its entries keyed by name
+func (r *nativeRepository) readTree(hash string) (map[string]treeEntry, error) {
+	entries := make(map[string]treeEntry)
+	if hash == "" {
+		return entries, nil
+	}
+
+	objType, data, err := r.objects.read(hash)
+	if err != nil {
+		return nil, err
+	}
+	if objType != "tree" {
+		return nil, fmt.Errorf("object %s is a %s, not a tree", hash, objType)
+	}
+
+	for len(data) > 0 {
+		mode, rest, ok := bytes.Cut(data, []byte{' '})
+		if !ok {
+			return nil, fmt.Errorf("malformed tree %s", hash)
+		}
+		name, rest, ok := bytes.Cut(rest, []byte{0})
+		if !ok || len(rest) < 20 {
+			return nil, fmt.Errorf("malformed tree %s", hash)
+		}
+		entries[string(name)] = treeEntry{mode: string(mode), hash: hex.EncodeToString(rest[:20])}
+		data = rest[20:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,154 @@
+package linter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/randilt/git-commit-linter/internal/ui"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultBaselinePath is where the baseline is created and looked for when
+// no other path is given
+const DefaultBaselinePath = ".git-commit-linter-baseline.yaml"
+
+const baselineHeader = `# Known violations in existing commits, created by
+# "git-commit-linter baseline create". Violations listed here are not
+# reported; new ones still fail.
+`
+
+// Baseline records known violations, as rule IDs per full commit hash, so
+// a repository with a long history can adopt the linter and only fail on
+// new problems.
+//
+// Example:
+//
+//	commits:
+//	  2f1e0c9b3d...:
+//	    - type-enum
+//	    - subject-max-length
+type Baseline struct {
+	Commits map[string][]string `yaml:"commits"`
+}
+
+// StaleEntry is a baseline entry whose commit no longer has the violation
+type StaleEntry struct {
+	CommitHash string
+	Rule       string
+}
+
+// LoadBaseline reads a baseline file
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Baseline
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %w", path, err)
+	}
+	if b.Commits == nil {
+		b.Commits = make(map[string][]string)
+	}
+	return &b, nil
+}
+
+// Save writes the baseline with commits and rules sorted, so regenerating
+// it gives a small diff
+func (b *Baseline) Save(path string) error {
+	for _, rules := range b.Commits {
+		sort.Strings(rules)
+	}
+
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(baselineHeader), data...), 0644)
+}
+
+// Len returns the number of entries in the baseline
+func (b *Baseline) Len() int {
+	n := 0
+	for _, rules := range b.Commits {
+		n += len(rules)
+	}
+	return n
+}
+
+func (b *Baseline) contains(hash, rule string) bool {
+	for _, r := range b.Commits[hash] {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// SetBaseline suppresses the violations recorded in b
+func (l *Linter) SetBaseline(b *Baseline) {
+	l.baseline = b
+}
+
+// CreateBaseline lints a range and records every violation found
+func (l *Linter) CreateBaseline(ctx context.Context, commitRange string) (*Baseline, error) {
+	b := &Baseline{Commits: make(map[string][]string)}
+	err := l.lintRange(ctx, commitRange, func(result commitResult) {
+		for _, v := range result.violations {
+			if !b.contains(result.commit.Hash, v.Rule) {
+				b.Commits[result.commit.Hash] = append(b.Commits[result.commit.Hash], v.Rule)
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+	return b, nil
+}
+
+// applyBaseline removes the violations of a commit that are in the
+// baseline. It returns the remaining violations, how many were suppressed
+// and the commit's baseline entries that did not match anything.
+func (l *Linter) applyBaseline(hash string, violations []Violation) ([]Violation, int, []StaleEntry) {
+	if l.baseline == nil || len(l.baseline.Commits[hash]) == 0 {
+		return violations, 0, nil
+	}
+
+	matched := make(map[string]bool)
+	var remaining []Violation
+	for _, v := range violations {
+		if l.baseline.contains(hash, v.Rule) {
+			matched[v.Rule] = true
+			continue
+		}
+		remaining = append(remaining, v)
+	}
+
+	var stale []StaleEntry
+	for _, rule := range l.baseline.Commits[hash] {
+		if !matched[rule] {
+			stale = append(stale, StaleEntry{CommitHash: hash, Rule: rule})
+		}
+	}
+	return remaining, len(violations) - len(remaining), stale
+}
+
+// printBaselineReport summarizes what the baseline suppressed and lists the
+// entries that can be removed from it
+func printBaselineReport(suppressed int, stale []StaleEntry) {
+	if suppressed == 0 && len(stale) == 0 {
+		return
+	}
+
+	ui.Section("Baseline")
+	if suppressed > 0 {
+		ui.Info(fmt.Sprintf("%d known violation(s) suppressed by the baseline", suppressed))
+	}
+	for _, entry := range stale {
+		ui.Warning(fmt.Sprintf("Commit %s no longer violates %s - remove it from the baseline or run %s",
+			ui.Bold(entry.CommitHash[:8]), ui.Bold(entry.Rule), ui.Bold("git-commit-linter baseline create")))
+	}
+}
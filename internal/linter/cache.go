@@ -0,0 +1,141 @@
+package linter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+)
+
+// cacheFormat is part of every fingerprint. Bump it when built-in rules
+// change in a way that is not visible in the config.
+const cacheFormat = "2"
+
+// CacheDir returns where lint results are cached for the repository whose
+// common git directory is commonDir
+func CacheDir(commonDir string) string {
+	return filepath.Join(commonDir, "git-commit-linter", "cache")
+}
+
+// ClearCache removes every cached result
+func ClearCache(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+// SetCacheDir enables caching results under dir. Commits never change, so
+// a commit already linted with the same config is not checked again. An
+// empty dir disables the cache.
+func (l *Linter) SetCacheDir(dir string) {
+	l.cacheDir = dir
+}
+
+// resultCache maps commit hashes to their violations for one config
+// fingerprint. Entries read from disk are only read during a run; new
+// results go to fresh so workers never race with the writer.
+type resultCache struct {
+	path    string
+	entries map[string][]Violation
+	fresh   map[string][]Violation
+}
+
+// openCache loads the results stored for the current config. The cache is
+// best-effort: it returns nil when caching is disabled or unavailable.
+func (l *Linter) openCache() *resultCache {
+	if l.cacheDir == "" {
+		return nil
+	}
+
+	c := &resultCache{
+		path:    filepath.Join(l.cacheDir, l.fingerprint()+".json"),
+		entries: make(map[string][]Violation),
+		fresh:   make(map[string][]Violation),
+	}
+	if data, err := os.ReadFile(c.path); err == nil {
+		// A corrupt file is dropped and rebuilt
+		if json.Unmarshal(data, &c.entries) != nil {
+			c.entries = make(map[string][]Violation)
+		}
+	}
+	return c
+}
+
+func (c *resultCache) lookup(hash string) ([]Violation, bool) {
+	if c == nil {
+		return nil, false
+	}
+	violations, ok := c.entries[hash]
+	return violations, ok
+}
+
+func (c *resultCache) store(hash string, violations []Violation) {
+	if c == nil || hash == uncommittedHash {
+		return
+	}
+	if _, ok := c.entries[hash]; !ok {
+		c.fresh[hash] = violations
+	}
+}
+
+// save writes the cache back if anything was added. The file is replaced
+// atomically so concurrent runs never see a partial write.
+func (c *resultCache) save() error {
+	if c == nil || len(c.fresh) == 0 {
+		return nil
+	}
+	for hash, violations := range c.fresh {
+		c.entries[hash] = violations
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "results-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// fingerprint hashes everything a commit's result depends on besides the
+// commit itself: the effective config, the keyword table used for
+// suggestions, the linter build and, when branch policies exist, the branch
+func (l *Linter) fingerprint() string {
+	h := sha256.New()
+	h.Write([]byte(cacheFormat))
+
+	if data, err := json.Marshal(l.config); err == nil {
+		h.Write(data)
+	}
+	if keywords, err := loadKeywordsOnce(); err == nil {
+		if data, err := json.Marshal(keywords); err == nil {
+			h.Write(data)
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		h.Write([]byte(info.Main.Version))
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" || setting.Key == "vcs.modified" {
+				h.Write([]byte(setting.Value))
+			}
+		}
+	}
+	if len(l.config.SpecialCommits.Branches) > 0 {
+		h.Write([]byte("branch:" + l.currentBranch()))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,125 @@
+package linter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Cleanup modes, as in git commit --cleanup
+const (
+	CleanupDefault    = "default"
+	CleanupStrip      = "strip"
+	CleanupWhitespace = "whitespace"
+	CleanupScissors   = "scissors"
+	CleanupVerbatim   = "verbatim"
+)
+
+// CleanupModes lists the modes SetCleanup accepts
+var CleanupModes = []string{CleanupDefault, CleanupStrip, CleanupWhitespace, CleanupScissors, CleanupVerbatim}
+
+// autoCommentChars are the characters core.commentChar=auto picks from
+const autoCommentChars = "#;@!$%^&|:"
+
+// scissorsLine follows the comment prefix on the line git commit -v and
+// --cleanup=scissors put above the diff; nothing below it is kept
+const scissorsLine = " ------------------------ >8 ------------------------"
+
+// Cleanup controls how a message file is cleaned up before it is linted,
+// so the linted text is what git will store. The zero value strips
+// comments starting with "#", like git commit with an editor.
+type Cleanup struct {
+	// Mode is one of CleanupModes. CleanupDefault means CleanupStrip; the
+	// caller should pick CleanupWhitespace when git did not open an editor.
+	Mode string
+	// CommentChar is core.commentChar: the prefix of comment lines, or
+	// "auto" to detect the one git picked
+	CommentChar string
+}
+
+// SetCleanup changes how LintCommitMessageFile and LintCommitMessageReader
+// clean up messages
+func (l *Linter) SetCleanup(c Cleanup) error {
+	switch c.Mode {
+	case "", CleanupDefault, CleanupStrip, CleanupWhitespace, CleanupScissors, CleanupVerbatim:
+	default:
+		return fmt.Errorf("unknown cleanup mode %q (want one of %s)", c.Mode, strings.Join(CleanupModes, ", "))
+	}
+	l.cleanup = c
+	return nil
+}
+
+// CommentPrefix returns the prefix of comment lines in message
+func (c Cleanup) CommentPrefix(message string) string {
+	switch c.CommentChar {
+	case "":
+		return "#"
+	case "auto":
+		return detectCommentChar(message)
+	}
+	return c.CommentChar
+}
+
+// detectCommentChar finds the comment character git picked for
+// core.commentChar=auto: the one starting the scissors line, or else the
+// one starting git's instructions at the end of the message
+func detectCommentChar(message string) string {
+	lines := strings.Split(message, "\n")
+	for _, line := range lines {
+		if len(line) > 0 && strings.ContainsRune(autoCommentChars, rune(line[0])) && line[1:] == scissorsLine {
+			return line[:1]
+		}
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			if strings.ContainsRune(autoCommentChars, rune(lines[i][0])) {
+				return lines[i][:1]
+			}
+			break
+		}
+	}
+	return "#"
+}
+
+// Clean returns the message git stores for a message file, following
+// git's cleanup modes:
+//
+//   - strip removes comment lines, then does what whitespace does
+//   - whitespace removes trailing whitespace and leading and trailing blank
+//     lines, and collapses runs of blank lines into one
+//   - scissors does what whitespace does after cutting the message at the
+//     scissors line
+//   - verbatim keeps the message as it is
+//
+// Everything below the scissors line is also cut in the strip and
+// whitespace modes, as git does for commit -v.
+func (c Cleanup) Clean(message string) string {
+	if c.Mode == CleanupVerbatim {
+		return message
+	}
+
+	prefix := c.CommentPrefix(message)
+	strip := c.Mode == "" || c.Mode == CleanupDefault || c.Mode == CleanupStrip
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(message, "\n") {
+		if line == prefix+scissorsLine {
+			break
+		}
+		if strip && strings.HasPrefix(line, prefix) {
+			continue
+		}
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			blank = true
+			continue
+		}
+		if blank && len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,112 @@
+package linter
+
+import (
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/git"
+	"github.com/randilt/git-commit-linter/internal/ui"
+)
+
+// Composition holds the answers given to the commit composer
+type Composition struct {
+	Type    string
+	Scope   string
+	Subject string
+	Body    string
+	// Breaking describes a breaking change, for the "BREAKING CHANGE:"
+	// footer
+	Breaking string
+	// Refs lists the issues the commit refers to, for the "Refs:" footer
+	Refs string
+}
+
+// Message returns the commit message for the answers. The body is
+// wrapped at 72 characters and footers follow it.
+func (c Composition) Message() string {
+	message := c.Type + optionalScope(c.Scope) + ": " + strings.TrimSpace(c.Subject)
+	if body := strings.TrimSpace(c.Body); body != "" {
+		message += "\n\n" + wrapBody(body)
+	}
+
+	var footers []string
+	if breaking := strings.TrimSpace(c.Breaking); breaking != "" {
+		footers = append(footers, wrapBody("BREAKING CHANGE: "+breaking))
+	}
+	if refs := strings.TrimSpace(c.Refs); refs != "" {
+		footers = append(footers, "Refs: "+refs)
+	}
+	if len(footers) > 0 {
+		message += "\n\n" + strings.Join(footers, "\n")
+	}
+	return message
+}
+
+// ComposeDefaults returns the answers the composer starts from: the type
+// and scope guessed from the branch name and the staged files, and the
+// issue key in the branch name, as in CommitTemplate
+func (l *Linter) ComposeDefaults(branch string) Composition {
+	files, _ := l.changedFiles(git.Commit{Hash: uncommittedHash})
+	c := Composition{
+		Type: l.templateType(branch),
+		Refs: issueKeyPattern.FindString(branch),
+	}
+	if c.Type != "" {
+		c.Scope = l.templateScope(branch, files)
+	}
+	return c
+}
+
+// TypeOptions returns the configured types, described from the keywords
+// file
+func (l *Linter) TypeOptions() []ui.Option {
+	var descriptions map[string]string
+	if keywords, err := loadKeywordsOnce(); err == nil {
+		descriptions = keywordDescriptions(keywords.CommitTypes)
+	}
+	options := make([]ui.Option, len(l.config.Types))
+	for i, t := range l.config.Types {
+		options[i] = ui.Option{Value: t, Description: descriptions[t]}
+	}
+	return options
+}
+
+// ScopeOptions returns the configured scopes, described from the keywords
+// file, or nil when the config allows any scope
+func (l *Linter) ScopeOptions() []ui.Option {
+	if len(l.config.Scopes) == 0 {
+		return nil
+	}
+	var descriptions map[string]string
+	if keywords, err := loadKeywordsOnce(); err == nil {
+		descriptions = keywordDescriptions(keywords.CommitScopes)
+	}
+	options := make([]ui.Option, len(l.config.Scopes))
+	for i, s := range l.config.Scopes {
+		options[i] = ui.Option{Value: s, Description: descriptions[s]}
+	}
+	return options
+}
+
+// ScopeRequired reports whether every commit needs a scope
+func (l *Linter) ScopeRequired() bool {
+	return l.config.Rules.RequireScope
+}
+
+// MaxSubjectLength returns the longest subject the rules allow
+func (l *Linter) MaxSubjectLength() int {
+	return l.config.Rules.MaxMessageLength
+}
+
+// CheckComposition lints the message for the answers, with the same rules
+// as lint-file, and returns the first error
+func (l *Linter) CheckComposition(c Composition) error {
+	return l.lintCommit(git.Commit{Hash: uncommittedHash, Message: c.Message()})
+}
+
+func keywordDescriptions(types []KeywordType) map[string]string {
+	descriptions := make(map[string]string, len(types))
+	for _, t := range types {
+		descriptions[t.Name] = t.Description
+	}
+	return descriptions
+}
@@ -0,0 +1,88 @@
+package linter
+
+import (
+	"fmt"
+
+	"github.com/randilt/git-commit-linter/internal/config"
+)
+
+// headerFields are the pieces of a successfully parsed header
+type headerFields struct {
+	Emoji   string
+	Type    string
+	Scope   string
+	Scopes  []string
+	Subject string
+}
+
+// checkCustomRules runs the config's custom_rules against a commit message.
+// header is nil when the header could not be parsed, in which case rules
+// targeting the subject or scope are skipped.
+func (l *Linter) checkCustomRules(message string, header *headerFields) []Violation {
+	if len(l.config.CustomRules) == 0 {
+		return nil
+	}
+
+	parts := splitMessage(message)
+
+	var violations []Violation
+	for i := range l.config.CustomRules {
+		rule := &l.config.CustomRules[i]
+
+		if rule.Target == config.TargetScope {
+			if header != nil && !passesForScopes(rule, header.Scopes) {
+				violations = append(violations, customRuleViolation(rule))
+			}
+			continue
+		}
+
+		var text string
+		switch rule.Target {
+		case config.TargetHeader:
+			text = parts.Header
+		case config.TargetBody:
+			text = parts.Body
+		case config.TargetFooter:
+			text = parts.Footer
+		case config.TargetMessage:
+			text = message
+		case config.TargetSubject:
+			if header == nil {
+				continue
+			}
+			text = header.Subject
+		}
+
+		if !rule.Passes(text) {
+			violations = append(violations, customRuleViolation(rule))
+		}
+	}
+
+	return violations
+}
+
+// passesForScopes applies a scope rule to every parsed scope, or to the
+// empty string when the header has none
+func passesForScopes(rule *config.CustomRule, scopes []string) bool {
+	if len(scopes) == 0 {
+		return rule.Passes("")
+	}
+	for _, scope := range scopes {
+		if !rule.Passes(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func customRuleViolation(rule *config.CustomRule) Violation {
+	msg := rule.Message
+	if msg == "" {
+		msg = fmt.Sprintf("%s does not satisfy rule", rule.Target)
+	}
+	return Violation{
+		Rule:     rule.Name,
+		Severity: rule.Severity,
+		Message:  fmt.Sprintf("%s (%s)", msg, rule.Name),
+	}
+}
@@ -0,0 +1,189 @@
+package linter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/randilt/git-commit-linter/internal/git"
+	"github.com/randilt/git-commit-linter/internal/ui"
+)
+
+// Reasons a commit in the range is not linted, worded to follow a count
+const (
+	SkipIgnoredAuthor  = "by ignored authors"
+	SkipIgnoredMessage = "with ignored messages"
+	SkipAuthor         = "not by --author"
+	SkipExcludedAuthor = "by --exclude-author"
+	SkipCommitter      = "not committed by --committer"
+	SkipDate           = "outside --since/--until"
+	SkipPath           = "not touching --path"
+	SkipGrep           = "not matching --grep"
+)
+
+// Filter selects the commits of a range to lint. Like git log, author and
+// committer patterns are regular expressions matched against
+// "Name <email>", dates compare against the committer date and a commit
+// must match one of several patterns of the same kind. Paths are globs as
+// in path_scopes, or directories that match everything beneath them.
+type Filter struct {
+	Authors        []string
+	ExcludeAuthors []string
+	Committers     []string
+	Since          time.Time
+	Until          time.Time
+	Paths          []string
+	Grep           []string
+
+	authors        []*regexp.Regexp
+	excludeAuthors []*regexp.Regexp
+	committers     []*regexp.Regexp
+	grep           []*regexp.Regexp
+}
+
+// SetFilter limits LintCommits to the commits the filter selects
+func (l *Linter) SetFilter(f Filter) error {
+	var err error
+	if f.authors, err = compilePatterns("--author", f.Authors); err != nil {
+		return err
+	}
+	if f.excludeAuthors, err = compilePatterns("--exclude-author", f.ExcludeAuthors); err != nil {
+		return err
+	}
+	if f.committers, err = compilePatterns("--committer", f.Committers); err != nil {
+		return err
+	}
+	if f.grep, err = compilePatterns("--grep", f.Grep); err != nil {
+		return err
+	}
+	l.filter = f
+	return nil
+}
+
+func compilePatterns(flag string, patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", flag, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// skipReason returns why a commit is not linted, or an empty string when it
+// is. Cheap checks come first; the path check has to ask git for the
+// commit's files.
+func (l *Linter) skipReason(commit git.Commit) string {
+	f := &l.filter
+	author := commit.Author.Name + " <" + commit.Author.Email + ">"
+
+	switch {
+	case l.config.Ignore.IgnoresAuthor(commit.Author.Name, commit.Author.Email):
+		return SkipIgnoredAuthor
+	case l.config.Ignore.IgnoresMessage(commit.Message):
+		return SkipIgnoredMessage
+	case len(f.authors) > 0 && !matchesAny(f.authors, author):
+		return SkipAuthor
+	case matchesAny(f.excludeAuthors, author):
+		return SkipExcludedAuthor
+	case len(f.committers) > 0 && !matchesAny(f.committers, commit.Committer.Name+" <"+commit.Committer.Email+">"):
+		return SkipCommitter
+	case !f.Since.IsZero() && commit.Committer.Date.Before(f.Since),
+		!f.Until.IsZero() && commit.Committer.Date.After(f.Until):
+		return SkipDate
+	case len(f.grep) > 0 && !matchesAny(f.grep, commit.Message):
+		return SkipGrep
+	case len(f.Paths) > 0 && !l.touchesPaths(commit):
+		return SkipPath
+	}
+	return ""
+}
+
+func matchesAny(patterns []*regexp.Regexp, text string) bool {
+	for _, re := range patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// touchesPaths reports whether the commit changes a file under the
+// filter's paths. Commits whose files cannot be listed are kept.
+func (l *Linter) touchesPaths(commit git.Commit) bool {
+	files, err := l.changedFiles(commit)
+	if err != nil {
+		return true
+	}
+	for _, file := range files {
+		for _, pattern := range l.filter.Paths {
+			dir := strings.TrimSuffix(pattern, "/")
+			if matchPath(pattern, file) || strings.HasPrefix(file, dir+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var relativeDatePattern = regexp.MustCompile(`^(\d+)\s*(minute|hour|day|week|month|year)s?\s+ago$`)
+
+// ParseDate parses the dates accepted by --since and --until: RFC 3339
+// timestamps, "2006-01-02" and "2006-01-02 15:04" in local time, and
+// relative dates such as "2 weeks ago"
+func ParseDate(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	m := relativeDatePattern.FindStringSubmatch(strings.ToLower(value))
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD, RFC 3339 or \"N days ago\")", value)
+	}
+	n, _ := strconv.Atoi(m[1])
+	switch m[2] {
+	case "minute":
+		return now.Add(-time.Duration(n) * time.Minute), nil
+	case "hour":
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	case "day":
+		return now.AddDate(0, 0, -n), nil
+	case "week":
+		return now.AddDate(0, 0, -7*n), nil
+	case "month":
+		return now.AddDate(0, -n, 0), nil
+	default:
+		return now.AddDate(-n, 0, 0), nil
+	}
+}
+
+// printSkipped reports how many commits were not linted and why
+func printSkipped(skipped map[string]int, total int) {
+	count := 0
+	reasons := make([]string, 0, len(skipped))
+	for reason, n := range skipped {
+		count += n
+		reasons = append(reasons, reason)
+	}
+	if count == 0 {
+		return
+	}
+
+	sort.Strings(reasons)
+	details := make([]string, len(reasons))
+	for i, reason := range reasons {
+		details[i] = fmt.Sprintf("%d %s", skipped[reason], reason)
+	}
+	ui.Info(fmt.Sprintf("Skipped %d of %d commit(s): %s", count, total, strings.Join(details, ", ")))
+}
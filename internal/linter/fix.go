@@ -0,0 +1,295 @@
+package linter
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/config"
+	"github.com/randilt/git-commit-linter/internal/git"
+	"github.com/randilt/git-commit-linter/internal/ui"
+)
+
+// bodyLineLength is the width FixMessage wraps body lines to
+const bodyLineLength = 72
+
+// looseHeaderPattern matches headers that are almost in the
+// type(scope): subject format, with stray spaces or a missing space after
+// the colon
+var looseHeaderPattern = regexp.MustCompile(`^(\w+)\s*(?:\(\s*([^()]*?)\s*\))?\s*:\s*(.+)$`)
+
+// listItemPattern matches the marker of a list item, which wrapped lines
+// are indented past
+var listItemPattern = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+`)
+
+// FixMessage applies the fixes that cannot change what a message means:
+// lowercasing the type, normalizing spaces in the header, removing a
+// trailing period from the subject, inserting the type the keyword engine
+// suggests when it is missing or unknown, inserting a required scope when
+// path_scopes implies exactly one, separating the header from the body and
+// wrapping long body lines. Merges are left alone; reverts and autosquash
+// commits keep git's wrapper and only the message inside it is fixed. It
+// returns the fixed message and a description of each fix applied. Scopes
+// are suggested from the staged files.
+func (l *Linter) FixMessage(message string) (string, []string) {
+	return l.fixMessage(git.Commit{Hash: uncommittedHash, Message: message})
+}
+
+// fixMessage fixes the message of a commit, suggesting scopes from the
+// files it changes
+func (l *Linter) fixMessage(commit git.Commit) (string, []string) {
+	if kind, original := classifySpecial(commit); kind != "" {
+		// The special commit policy decides what is checked, so there is
+		// nothing to fix unless the referenced message is linted
+		if kind == kindMerge || l.specialPolicy(kind) != config.PolicyLint {
+			return commit.Message, nil
+		}
+		return l.fixSpecial(commit, original)
+	}
+
+	var fixes []string
+	header, rest, _ := strings.Cut(commit.Message, "\n")
+
+	fixedHeader, headerFixes := l.fixHeader(header, commit)
+	fixes = append(fixes, headerFixes...)
+
+	if rest != "" && !strings.HasPrefix(rest, "\n") {
+		rest = "\n" + rest
+		fixes = append(fixes, "added a blank line after the header")
+	}
+	if wrapped := wrapBody(rest); wrapped != rest {
+		rest = wrapped
+		fixes = append(fixes, fmt.Sprintf("wrapped body lines at %d characters", bodyLineLength))
+	}
+
+	if rest == "" {
+		return fixedHeader, fixes
+	}
+	return fixedHeader + "\n" + rest, fixes
+}
+
+// fixSpecial fixes the message a revert or autosquash commit refers to and
+// puts it back inside git's wrapper, so that git still recognizes the
+// commit
+func (l *Linter) fixSpecial(commit git.Commit, original string) (string, []string) {
+	header, rest, _ := strings.Cut(commit.Message, "\n")
+	fixed, fixes := l.fixMessage(git.Commit{Hash: commit.Hash, Message: original})
+	if len(fixes) == 0 {
+		return commit.Message, nil
+	}
+
+	if m := autosquashPattern.FindStringSubmatch(header); m != nil {
+		if m[1] == "amend" && strings.TrimSpace(rest) != "" {
+			// The body of an amend! commit is the replacement message
+			return header + "\n\n" + fixed, fixes
+		}
+		header = m[1] + "! " + fixed
+	} else {
+		header = `Revert "` + fixed + `"`
+	}
+	if rest == "" {
+		return header, fixes
+	}
+	return header + "\n" + rest, fixes
+}
+
+// fixHeader fixes the first line of a message
+func (l *Linter) fixHeader(header string, commit git.Commit) (string, []string) {
+	var fixes []string
+	emoji, rest := splitEmojiPrefix(strings.TrimSpace(header))
+
+	var commitType, scope, subject string
+	if m := looseHeaderPattern.FindStringSubmatch(rest); m != nil {
+		commitType, scope, subject = m[1], m[2], strings.TrimSpace(m[3])
+		if canonical := commitType + optionalScope(scope) + ": " + subject; canonical != rest {
+			fixes = append(fixes, "normalized spacing in the header")
+		}
+		if lower := strings.ToLower(commitType); lower != commitType && l.allowedType(lower) {
+			commitType = lower
+			fixes = append(fixes, "lowercased the type")
+		}
+	} else {
+		subject = rest
+	}
+
+	if !l.allowedType(commitType) {
+		suggested := l.suggestedType(rest)
+		if suggested == "" {
+			// Without a type to put in, the rest of the header is left as is
+			return header, nil
+		}
+		if commitType == "" {
+			fixes = append(fixes, fmt.Sprintf("inserted the type '%s'", suggested))
+		} else {
+			fixes = append(fixes, fmt.Sprintf("replaced the type '%s' with '%s'", commitType, suggested))
+		}
+		commitType = suggested
+	}
+
+	if scope == "" && l.config.Rules.RequireScope {
+		if scope = l.suggestedScope(commit); scope != "" {
+			fixes = append(fixes, fmt.Sprintf("inserted the scope '%s'", scope))
+		}
+	}
+
+	if trimmed := strings.TrimSuffix(subject, "."); trimmed != subject && !strings.HasSuffix(trimmed, ".") {
+		subject = strings.TrimSpace(trimmed)
+		fixes = append(fixes, "removed the trailing period")
+	}
+
+	fixed := commitType + optionalScope(scope) + ": " + subject
+	if emoji != "" {
+		fixed = emoji + " " + fixed
+	}
+	if len(fixes) == 0 {
+		return header, nil
+	}
+	return fixed, fixes
+}
+
+func optionalScope(scope string) string {
+	if scope == "" {
+		return ""
+	}
+	return "(" + scope + ")"
+}
+
+// suggestedType returns the configured type the keyword engine finds in
+// the header, or an empty string when no keyword matches
+func (l *Linter) suggestedType(header string) string {
+	keywords, err := loadKeywordsOnce()
+	if err != nil {
+		return ""
+	}
+	correction, err := SuggestCorrection(header, keywords)
+	if err != nil || correction.Score == 0 || !l.allowedType(correction.Type) {
+		return ""
+	}
+	return correction.Type
+}
+
+// suggestedScope returns the scope path_scopes implies for the files the
+// commit changes, if there is exactly one and the config allows it. Scopes
+// the keyword engine finds are not used: a single word can tip them.
+func (l *Linter) suggestedScope(commit git.Commit) string {
+	files, _ := l.changedFiles(commit)
+	implied := l.impliedScopes(files)
+	if len(implied) != 1 {
+		return ""
+	}
+	return l.allowedScope(implied[0])
+}
+
+// wrapBody wraps the prose lines of a message body. Indented lines, code
+// fences, trailers and words longer than a line are left alone.
+func wrapBody(body string) string {
+	lines := strings.Split(body, "\n")
+	var wrapped []string
+	fenced := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			fenced = !fenced
+		}
+		if fenced || len(line) <= bodyLineLength || strings.HasPrefix(line, " ") ||
+			strings.HasPrefix(line, "\t") || trailerPattern.MatchString(line) {
+			wrapped = append(wrapped, line)
+			continue
+		}
+		wrapped = append(wrapped, wrapLine(line)...)
+	}
+	return strings.Join(wrapped, "\n")
+}
+
+// wrapLine breaks a line at spaces, indenting the continuation lines of a
+// list item past its marker
+func wrapLine(line string) []string {
+	indent := ""
+	if m := listItemPattern.FindString(line); m != "" {
+		indent = strings.Repeat(" ", len(m))
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(line) {
+		switch {
+		case current == "":
+			current = word
+			if len(lines) > 0 {
+				current = indent + word
+			}
+		case len(current)+1+len(word) > bodyLineLength:
+			lines = append(lines, current)
+			current = indent + word
+		default:
+			current += " " + word
+		}
+	}
+	return append(lines, current)
+}
+
+// FixCommitMessageFile applies FixMessage to a message file, rewriting it
+// with the message git would store, shows what changed and lints the
+// result
+func (l *Linter) FixCommitMessageFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message file: %w", err)
+	}
+
+	message := l.cleanup.Clean(string(content))
+	fixed, fixes := l.FixMessage(message)
+	if len(fixes) > 0 {
+		if err := os.WriteFile(path, []byte(fixed+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write commit message file: %w", err)
+		}
+
+		ui.Section("Applied Fixes")
+		for _, fix := range fixes {
+			ui.Success(fix)
+		}
+		ui.CodeBlock(diffLines(message, fixed))
+	}
+
+	return l.LintCommitMessage(fixed)
+}
+
+// diffLines returns a line diff of two short texts, with "-" before
+// removed lines and "+" before added ones
+func diffLines(before, after string) string {
+	a, b := strings.Split(before, "\n"), strings.Split(after, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return strings.TrimRight(diff.String(), "\n")
+}
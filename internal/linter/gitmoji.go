@@ -0,0 +1,146 @@
+package linter
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/randilt/git-commit-linter/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+// RuleHeaderEmoji is reported when the emoji prefix breaks the header.emoji settings
+const RuleHeaderEmoji = "header-emoji"
+
+//go:embed gitmoji.yaml
+var gitmojiData []byte
+
+// Gitmoji is an entry of the embedded gitmoji table
+type Gitmoji struct {
+	Code        string   `yaml:"code"`
+	Emoji       string   `yaml:"emoji"`
+	Description string   `yaml:"description"`
+	Types       []string `yaml:"types"`
+}
+
+var (
+	gitmojiOnce   sync.Once
+	gitmojiByCode map[string]*Gitmoji
+	gitmojiByChar map[string]*Gitmoji
+)
+
+// shortcodePrefix matches a leading ":shortcode: " in a header
+var shortcodePrefix = regexp.MustCompile(`^(:[a-z0-9_+\-]+:)\s+`)
+
+// LookupGitmoji finds a gitmoji by shortcode (":sparkles:") or by the emoji
+// character itself. Variation selectors are ignored when comparing.
+func LookupGitmoji(emoji string) (*Gitmoji, bool) {
+	gitmojiOnce.Do(loadGitmojis)
+	if g, ok := gitmojiByCode[emoji]; ok {
+		return g, true
+	}
+	g, ok := gitmojiByChar[normalizeEmoji(emoji)]
+	return g, ok
+}
+
+func loadGitmojis() {
+	var table []Gitmoji
+	if err := yaml.Unmarshal(gitmojiData, &table); err != nil {
+		panic("linter: invalid embedded gitmoji table: " + err.Error())
+	}
+
+	gitmojiByCode = make(map[string]*Gitmoji, len(table))
+	gitmojiByChar = make(map[string]*Gitmoji, len(table))
+	for i := range table {
+		g := &table[i]
+		gitmojiByCode[g.Code] = g
+		gitmojiByChar[normalizeEmoji(g.Emoji)] = g
+	}
+}
+
+// normalizeEmoji drops variation selectors so "⚡" and "⚡️" compare equal
+func normalizeEmoji(s string) string {
+	return strings.ReplaceAll(s, "\ufe0f", "")
+}
+
+// splitEmojiPrefix separates a leading gitmoji shortcode or emoji from the
+// rest of the header. It returns an empty emoji when there is none.
+func splitEmojiPrefix(header string) (emoji, rest string) {
+	if m := shortcodePrefix.FindStringSubmatch(header); m != nil {
+		return m[1], header[len(m[0]):]
+	}
+
+	// Consume a pictographic character together with any variation
+	// selectors, skin tone modifiers and zero-width-joined sequences
+	i := 0
+	for i < len(header) {
+		r, size := utf8.DecodeRuneInString(header[i:])
+		switch {
+		case i == 0 && !isEmojiRune(r):
+			return "", header
+		case r == '\u200d':
+			// Joiner: the next rune belongs to the same emoji
+			i += size
+			if i < len(header) {
+				_, next := utf8.DecodeRuneInString(header[i:])
+				i += next
+			}
+			continue
+		case i > 0 && !isEmojiModifier(r):
+			if !unicode.IsSpace(r) {
+				return "", header
+			}
+			return header[:i], strings.TrimLeftFunc(header[i:], unicode.IsSpace)
+		}
+		i += size
+	}
+	return "", header
+}
+
+func isEmojiRune(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0x1f000 && r <= 0x1faff)
+}
+
+func isEmojiModifier(r rune) bool {
+	return r == '\ufe0f' || (r >= 0x1f3fb && r <= 0x1f3ff)
+}
+
+// checkEmoji validates the emoji prefix of a parsed header against the
+// header.emoji settings
+func (l *Linter) checkEmoji(header *headerFields) *Violation {
+	mode := l.config.Header.Emoji
+	if mode == "" {
+		mode = config.EmojiForbidden
+	}
+
+	if header.Emoji == "" {
+		if mode == config.EmojiRequired {
+			return errorViolation(RuleHeaderEmoji, "header must start with a gitmoji, e.g. ':sparkles: feat: ...'")
+		}
+		return nil
+	}
+
+	if mode == config.EmojiForbidden {
+		return errorViolation(RuleHeaderEmoji, "header must not start with an emoji ('%s')", header.Emoji)
+	}
+
+	g, ok := LookupGitmoji(header.Emoji)
+	if !ok {
+		return errorViolation(RuleHeaderEmoji, "unknown gitmoji '%s'", header.Emoji)
+	}
+
+	if l.config.Header.EmojiMatchesType {
+		for _, t := range g.Types {
+			if t == header.Type {
+				return nil
+			}
+		}
+		return errorViolation(RuleHeaderEmoji, "gitmoji '%s' (%s) does not match type '%s', expected one of: %s",
+			header.Emoji, g.Description, header.Type, strings.Join(g.Types, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package linter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/randilt/git-commit-linter/internal/config"
+	"github.com/randilt/git-commit-linter/internal/git"
+)
+
+// Reword is a commit that fails linting and the message fix-history would
+// give it
+type Reword struct {
+	Commit git.Commit
+	// Message is the original message with FixMessage's fixes applied
+	Message string
+	// Problems are the errors in the original message
+	Problems []Violation
+	// Fixed is set when Message passes linting
+	Fixed bool
+}
+
+// PlanRewords lints a range and returns the commits that fail, oldest
+// first, with their messages fixed as far as FixMessage can. Commits the
+// filters skip and violations in the baseline are left alone.
+func (l *Linter) PlanRewords(ctx context.Context, commitRange string) ([]Reword, error) {
+	var rewords []Reword
+	err := l.lintRange(ctx, commitRange, func(result commitResult) {
+		if result.skipped != "" {
+			return
+		}
+		violations, _, _ := l.applyBaseline(result.commit.Hash, result.violations)
+		problems := errorsOnly(violations)
+		if len(problems) == 0 {
+			return
+		}
+
+		message, fixed := l.proposedMessage(result.commit)
+		rewords = append(rewords, Reword{
+			Commit:   result.commit,
+			Message:  message,
+			Problems: problems,
+			Fixed:    fixed,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+
+	// The range is read newest first; the rebase goes the other way
+	for i, j := 0, len(rewords)-1; i < j; i, j = i+1, j-1 {
+		rewords[i], rewords[j] = rewords[j], rewords[i]
+	}
+	return rewords, nil
+}
+
+// proposedMessage returns a commit's message with FixMessage's fixes
+// applied, and whether the result passes linting
+func (l *Linter) proposedMessage(commit git.Commit) (string, bool) {
+	commit.Message = Cleanup{Mode: CleanupWhitespace}.Clean(commit.Message)
+	commit.Message, _ = l.fixMessage(commit)
+	return commit.Message, firstError(l.checkCommit(commit)) == nil
+}
+
+func errorsOnly(violations []Violation) []Violation {
+	var errs []Violation
+	for _, v := range violations {
+		if v.Severity == config.SeverityError {
+			errs = append(errs, v)
+		}
+	}
+	return errs
+}
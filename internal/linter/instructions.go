@@ -0,0 +1,260 @@
+package linter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/git"
+)
+
+// commitPlacement is where a commit sits relative to HEAD, which decides
+// how its message can be changed
+type commitPlacement struct {
+	// head is set when the commit is the checked out one
+	head bool
+	// onBranch is set when HEAD contains the commit
+	onBranch bool
+	// published is set when a remote-tracking branch contains the commit
+	published bool
+	// mergesAfter is set when merge commits sit between the commit and HEAD
+	mergesAfter bool
+}
+
+// history is what fix instructions need to know about the commits leading
+// to HEAD. It is read once per run, however many commits fail.
+type history struct {
+	head string
+	// unpublished maps the commits HEAD has and no published ref has to
+	// their parents
+	unpublished map[string][]string
+	// all maps every commit HEAD has to its parents. It is only read once a
+	// published commit fails.
+	all map[string][]string
+}
+
+// SetPushedTip makes fix instructions describe the clone a push comes
+// from: tip is taken for its checked out commit, and commits reachable from
+// the refs starting with existingRefs for published ones. Server-side
+// hooks run in a bare repository whose HEAD has nothing to do with the
+// push.
+func (l *Linter) SetPushedTip(tip, existingRefs string) {
+	l.tip, l.publishedRefs, l.history = tip, existingRefs, nil
+}
+
+// loadHistory resolves HEAD, or the pushed tip, and reads the commits no
+// published ref has
+func (l *Linter) loadHistory() (*history, error) {
+	if l.history != nil {
+		return l.history, nil
+	}
+	tip, published := l.tip, l.publishedRefs
+	if tip == "" {
+		tip = "HEAD"
+	}
+	if published == "" {
+		published = git.RemoteRefs
+	}
+
+	head, err := l.repo.ResolveRevision(tip)
+	if err != nil {
+		return nil, err
+	}
+	h := &history{head: head}
+	h.unpublished, err = l.parentMap(git.Revisions{Include: head, ExcludeRefs: []string{published}})
+	if err != nil {
+		return nil, err
+	}
+	l.history = h
+	return h, nil
+}
+
+// parentMap maps the commits revs selects to their parents
+func (l *Linter) parentMap(revs git.Revisions) (map[string][]string, error) {
+	parents := make(map[string][]string)
+	err := l.repo.StreamRevisions(context.Background(), revs, func(c git.Commit) error {
+		parents[c.Hash] = c.Parents
+		return nil
+	})
+	return parents, err
+}
+
+// locateCommit finds where a commit sits relative to HEAD
+func (l *Linter) locateCommit(commit git.Commit) (commitPlacement, error) {
+	var p commitPlacement
+	h, err := l.loadHistory()
+	if err != nil {
+		return p, err
+	}
+
+	// Any merge after an unpublished commit is unpublished too, so the
+	// unpublished commits are enough to place one
+	graph := h.unpublished
+	if _, ok := graph[commit.Hash]; !ok {
+		if h.all == nil {
+			if h.all, err = l.parentMap(git.Revisions{Include: h.head}); err != nil {
+				return p, err
+			}
+		}
+		graph = h.all
+		p.published = true
+	}
+	if _, ok := graph[commit.Hash]; !ok {
+		return commitPlacement{}, nil
+	}
+
+	p.onBranch = true
+	p.head = commit.Hash == h.head
+	if !p.head {
+		p.mergesAfter = hasMergesAfter(graph, h.head, commit.Hash)
+	}
+	return p, nil
+}
+
+// hasMergesAfter reports whether the graph has merge commits that head
+// reaches and base does not, like git rev-list --merges base..head
+func hasMergesAfter(graph map[string][]string, head, base string) bool {
+	hidden := make(map[string]bool)
+	stack := []string{base}
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if parents, ok := graph[hash]; ok && !hidden[hash] {
+			hidden[hash] = true
+			stack = append(stack, parents...)
+		}
+	}
+
+	seen := make(map[string]bool)
+	stack = []string{head}
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		parents, ok := graph[hash]
+		if !ok || hidden[hash] || seen[hash] {
+			continue
+		}
+		if len(parents) > 1 {
+			return true
+		}
+		seen[hash] = true
+		stack = append(stack, parents...)
+	}
+	return false
+}
+
+// getFixInstructions returns the commands that reword a failing commit,
+// which depend on whether it is HEAD, whether it was pushed and whether a
+// rebase would have to replay merges. The proposed message is the one
+// FixMessage produces, when that passes linting.
+func (l *Linter) getFixInstructions(commit git.Commit) string {
+	var instructions strings.Builder
+	instructions.WriteString("Fix Instructions:\n")
+
+	hash := abbrev(commit.Hash)
+	message, fixed := l.proposedMessage(commit)
+	placement, err := l.locate(commit)
+	if err != nil {
+		// Without a readable history nothing is known about the commit,
+		// and a rebase works for any commit on the branch
+		placement = commitPlacement{onBranch: true}
+	}
+	merge := len(commit.Parents) > 1
+
+	switch {
+	case !placement.onBranch:
+		instructions.WriteString(fmt.Sprintf("- Commit %s is not on the checked out branch\n", hash))
+		instructions.WriteString("  Check out a branch that contains it, then lint again\n")
+		return instructions.String()
+
+	case placement.head:
+		instructions.WriteString("- Latest commit: amend it\n")
+		if fixed {
+			instructions.WriteString("  git commit --amend" + messageArgs(message) + "\n")
+		} else {
+			instructions.WriteString("  git commit --amend\n")
+			instructions.WriteString("  In the editor, " + rewriteHint(commit) + "\n")
+		}
+
+	case merge || placement.mergesAfter:
+		instructions.WriteString("- Older commit with merges up to HEAD: reword it in a rebase that keeps them\n")
+		instructions.WriteString(fmt.Sprintf("  git rebase -i --rebase-merges %s\n", rebaseBase(commit)))
+		if merge {
+			instructions.WriteString(fmt.Sprintf("  Change 'merge -C %s' to 'merge -c %s'", hash, hash))
+		} else {
+			instructions.WriteString(fmt.Sprintf("  Change 'pick %s' to 'reword %s'", hash, hash))
+		}
+		writeProposal(&instructions, commit, message, fixed)
+
+	default:
+		instructions.WriteString("- Older commit: reword it with an interactive rebase\n")
+		instructions.WriteString(fmt.Sprintf("  git rebase -i %s\n", rebaseBase(commit)))
+		instructions.WriteString(fmt.Sprintf("  Change 'pick %s' to 'reword %s'", hash, hash))
+		writeProposal(&instructions, commit, message, fixed)
+		if fixed {
+			historyRange := "HEAD"
+			if len(commit.Parents) > 0 {
+				historyRange = abbrev(commit.Parents[0]) + "..HEAD"
+			}
+			instructions.WriteString("- Or apply the fixes to it and to the failing commits after it\n")
+			instructions.WriteString(fmt.Sprintf("  git-commit-linter fix-history --range=%s\n", historyRange))
+		}
+	}
+
+	if placement.published {
+		instructions.WriteString("- The commit is already pushed, so this rewrites published history\n")
+		instructions.WriteString("  Agree on it with your team, then push with\n")
+		instructions.WriteString("  git push --force-with-lease\n")
+	}
+	return instructions.String()
+}
+
+// writeProposal ends a rebase instruction with the message to use
+func writeProposal(instructions *strings.Builder, commit git.Commit, message string, fixed bool) {
+	if !fixed {
+		instructions.WriteString(", then " + rewriteHint(commit) + "\n")
+		return
+	}
+	instructions.WriteString(" and use this message:\n")
+	for _, line := range strings.Split(message, "\n") {
+		instructions.WriteString(strings.TrimRight("    "+line, " ") + "\n")
+	}
+}
+
+// rewriteHint says how to rewrite a message by hand. Reverts and autosquash
+// commits keep the line git recognizes them by.
+func rewriteHint(commit git.Commit) string {
+	switch kind, _ := classifySpecial(commit); kind {
+	case kindRevert:
+		return `rewrite the quoted message as type(scope): subject, keeping Revert "..." around it`
+	case kindAutosquash:
+		return "rewrite the message it refers to as type(scope): subject, keeping the fixup!, squash! or amend! line"
+	}
+	return "rewrite the message as type(scope): subject"
+}
+
+// messageArgs returns the -m arguments that give git commit a message,
+// one per paragraph as git joins them
+func messageArgs(message string) string {
+	var args strings.Builder
+	for _, paragraph := range strings.Split(message, "\n\n") {
+		args.WriteString(" -m " + git.ShellQuote(paragraph))
+	}
+	return args.String()
+}
+
+// rebaseBase returns the argument of git rebase -i that makes a commit
+// the first in the todo list
+func rebaseBase(commit git.Commit) string {
+	if len(commit.Parents) == 0 {
+		return "--root"
+	}
+	return abbrev(commit.Parents[0])
+}
+
+func abbrev(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
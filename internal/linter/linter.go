@@ -1,10 +1,14 @@
 package linter
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/randilt/git-commit-linter/internal/config"
 	"github.com/randilt/git-commit-linter/internal/git"
@@ -12,14 +16,63 @@ import (
 )
 
 type Linter struct {
-	config *config.Config
+	config       *config.Config
+	repo         git.Repository
+	changedFiles func(git.Commit) ([]string, error)
+	locate       func(git.Commit) (commitPlacement, error)
+
+	workers  int
+	cacheDir string
+	baseline *Baseline
+	filter   Filter
+	cleanup  Cleanup
+
+	branch     string
+	branchOnce sync.Once
+
+	// tip and publishedRefs replace HEAD and the remote-tracking refs in
+	// fix instructions, see SetPushedTip
+	tip           string
+	publishedRefs string
+	history       *history
 }
 
 type LintError struct {
 	CommitHash string
 	Message    string
 	FixSteps   string
+	Scopes     []string
+	Violations []Violation
 }
+
+// Violation is a single rule failure found in a commit message. Message
+// is plain text, since results are cached whatever the terminal supports.
+type Violation struct {
+	Rule     string
+	Severity string
+	Message  string
+	// Suggestion is the correction Message ends with, if any, which is
+	// printed in bold
+	Suggestion string
+}
+
+// styled returns the message as printed, with its suggestion in bold
+func (v Violation) styled() string {
+	prefix, ok := strings.CutSuffix(v.Message, v.Suggestion)
+	if v.Suggestion == "" || !ok {
+		return v.Message
+	}
+	return prefix + ui.Bold(v.Suggestion)
+}
+
+// Built-in rule IDs
+const (
+	RuleHeaderFormat  = "header-format"
+	RuleTypeEnum      = "type-enum"
+	RuleScopeRequired = "scope-required"
+	RuleSubjectLength = "subject-max-length"
+)
+
 type ValidationError struct {
 	Message string
 }
@@ -29,20 +82,36 @@ func (e *ValidationError) Error() string {
 }
 
 func New(cfg *config.Config) *Linter {
-	return &Linter{config: cfg}
+	l := &Linter{config: cfg, repo: git.ExecRepository{}}
+	l.changedFiles = l.commitFiles
+	l.locate = l.locateCommit
+	return l
+}
+
+// SetRepository changes the repository commits are read from. By default
+// the linter runs the git binary.
+func (l *Linter) SetRepository(repo git.Repository) {
+	l.repo = repo
 }
 
 // LintCommitMessage lints a single commit message from a string
 func (l *Linter) LintCommitMessage(message string) error {
 	// Create a temporary commit object to reuse existing logic
 	tempCommit := git.Commit{
-		Hash:    "UNCOMMITTED",
+		Hash:    uncommittedHash,
 		Message: message,
 	}
 
-	if err := l.lintCommit(tempCommit); err != nil {
+	violations := l.checkCommit(tempCommit)
+	printWarnings(violations)
+
+	if err := firstError(violations); err != nil {
 		ui.Section("Linting Issues Found")
-		ui.Error(err.Error())
+		for _, v := range violations {
+			if v.Severity == config.SeverityError {
+				ui.Error(v.styled())
+			}
+		}
 
 		ui.Section("Reference Information")
 		ui.Info(fmt.Sprintf("Valid commit format: %s",
@@ -59,27 +128,28 @@ func (l *Linter) LintCommitMessage(message string) error {
 
 // LintCommitMessageFile lints a commit message from a file path
 func (l *Linter) LintCommitMessageFile(filepath string) error {
-	content, err := os.ReadFile(filepath)
+	file, err := os.Open(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to read commit message file: %w", err)
 	}
+	defer file.Close()
+	return l.LintCommitMessageReader(file)
+}
 
-	// Clean the message - remove comments and empty lines
-	lines := strings.Split(string(content), "\n")
-	var messageLines []string
-	for _, line := range lines {
-		if !strings.HasPrefix(strings.TrimSpace(line), "#") && len(strings.TrimSpace(line)) > 0 {
-			messageLines = append(messageLines, line)
-		}
+// LintCommitMessageReader lints a commit message read from r, such as
+// standard input, after cleaning it up as git would (see SetCleanup)
+func (l *Linter) LintCommitMessageReader(r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message: %w", err)
 	}
-	message := strings.Join(messageLines, "\n")
 
-	return l.LintCommitMessage(strings.TrimSpace(message))
+	return l.LintCommitMessage(l.cleanup.Clean(string(content)))
 }
 
 func (l *Linter) SuggestMessageCorrection(message string) (string, error) {
 	// Load keywords configuration
-	keywords, err := LoadKeywords()
+	keywords, err := loadKeywordsOnce()
 	if err != nil {
 		return "", fmt.Errorf("failed to load keywords: %w", err)
 	}
@@ -128,20 +198,81 @@ func (l *Linter) SuggestMessageCorrection(message string) (string, error) {
 //
 // Returns nil if all commits pass validation, or error details if any commits fail.
 func (l *Linter) LintCommits(commitRange string) error {
-	commits, err := git.GetCommits(commitRange)
+	return l.LintCommitsContext(context.Background(), commitRange)
+}
+
+// LintCommitsContext is LintCommits with a context. Commits are streamed
+// from the repository and checked in parallel, see SetWorkers; cancelling
+// ctx stops reading the log.
+func (l *Linter) LintCommitsContext(ctx context.Context, commitRange string) error {
+	return l.lintCommits(ctx, l.rangeStream(commitRange))
+}
+
+// LintRevisionsContext is LintCommitsContext for the commits revs selects,
+// such as those of a branch being pushed for the first time
+func (l *Linter) LintRevisionsContext(ctx context.Context, revs git.Revisions) error {
+	return l.lintCommits(ctx, func(ctx context.Context, fn func(git.Commit) error) error {
+		return l.repo.StreamRevisions(ctx, revs, fn)
+	})
+}
+
+// lintCommits lints the streamed commits and reports the problems found
+func (l *Linter) lintCommits(ctx context.Context, stream commitStream) error {
+	var lintErrors []LintError
+	// failed holds the commits of lintErrors, whose fix instructions are
+	// written once the stream has stopped reading the repository
+	var failed []git.Commit
+	var warnings []LintError
+	var stale []StaleEntry
+	suppressed := 0
+	total := 0
+	skipped := make(map[string]int)
+	err := l.lintStream(ctx, stream, func(result commitResult) {
+		total++
+		if result.skipped != "" {
+			skipped[result.skipped]++
+			return
+		}
+
+		commit := result.commit
+		violations, known, staleEntries := l.applyBaseline(commit.Hash, result.violations)
+		suppressed += known
+		stale = append(stale, staleEntries...)
+		if len(violations) == 0 {
+			return
+		}
+
+		lintError := LintError{
+			CommitHash: commit.Hash[:8],
+			Violations: violations,
+		}
+		if header, _ := l.parseHeader(commit.Message); header != nil {
+			lintError.Scopes = header.Scopes
+		}
+		if err := firstError(violations); err != nil {
+			lintError.Message = err.Error()
+			lintErrors = append(lintErrors, lintError)
+			failed = append(failed, commit)
+		} else {
+			warnings = append(warnings, lintError)
+		}
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get commits: %w", err)
 	}
+	for i, commit := range failed {
+		lintErrors[i].FixSteps = l.getFixInstructions(commit)
+	}
 
-	var lintErrors []LintError
-	for _, commit := range commits {
-		if err := l.lintCommit(commit); err != nil {
-			lintError := LintError{
-				CommitHash: commit.Hash[:8],
-				Message:    err.Error(),
-				FixSteps:   l.getFixInstructions(commit),
+	printSkipped(skipped, total)
+	printBaselineReport(suppressed, stale)
+
+	if len(warnings) > 0 {
+		ui.Section("Linting Warnings")
+		for _, w := range warnings {
+			for _, v := range w.Violations {
+				ui.Warning(fmt.Sprintf("Commit %s: %s", ui.Bold(w.CommitHash), v.styled()))
 			}
-			lintErrors = append(lintErrors, lintError)
 		}
 	}
 
@@ -150,7 +281,13 @@ func (l *Linter) LintCommits(commitRange string) error {
 
 		// Print each error with its fix instructions
 		for _, err := range lintErrors {
-			ui.Error(fmt.Sprintf("Commit %s: %s", ui.Bold(err.CommitHash), err.Message))
+			for _, v := range err.Violations {
+				if v.Severity == config.SeverityError {
+					ui.Error(fmt.Sprintf("Commit %s: %s", ui.Bold(err.CommitHash), v.styled()))
+				} else {
+					ui.Warning(fmt.Sprintf("Commit %s: %s", ui.Bold(err.CommitHash), v.styled()))
+				}
+			}
 			ui.CodeBlock(err.FixSteps)
 		}
 
@@ -161,54 +298,61 @@ func (l *Linter) LintCommits(commitRange string) error {
 			ui.Bold(strings.Join(l.config.Types, ", "))))
 
 		ui.Error("Some commits failed linting - please fix the issues above")
-		return nil
+		return &ValidationError{Message: fmt.Sprintf("%d commit(s) failed linting", len(lintErrors))}
 	}
 
 	ui.Success("All commits passed linting!")
 	return nil
 }
 
-func (l *Linter) getFixInstructions(commit git.Commit) string {
-	var instructions strings.Builder
-	instructions.WriteString("Fix Instructions:\n")
+// lintCommit returns the first error-level violation of a commit, if any
+func (l *Linter) lintCommit(commit git.Commit) error {
+	return firstError(l.checkCommit(commit))
+}
 
-	// Check if this is the latest commit
-	isLatestCommit := strings.Contains(commit.Hash, "HEAD")
-	if isLatestCommit {
-		instructions.WriteString("- Latest commit: Use amend\n")
-		instructions.WriteString("  git commit --amend -m \"type(scope): your message\"\n")
-	} else {
-		instructions.WriteString("- Older commit: Use interactive rebase\n")
-		instructions.WriteString(fmt.Sprintf("  git rebase -i %s~1\n", commit.Hash[:8]))
-		instructions.WriteString("  Change 'pick' to 'reword' for the target commit\n")
+// checkCommit runs the special commit policy, built-in checks, secret
+// detection and custom rules against a commit
+func (l *Linter) checkCommit(commit git.Commit) []Violation {
+	// Secrets are checked on the message as written, even for commits the
+	// special commit policy ignores
+	secrets := l.checkSecrets(commit.Message)
+
+	commit, violations, handled := l.checkSpecial(commit)
+	if handled {
+		return append(violations, secrets...)
 	}
 
-	return instructions.String()
-}
+	header, v := l.checkHeader(commit)
+	if v != nil {
+		violations = append(violations, *v)
+	}
+	if v := l.checkPathScopes(commit, header); v != nil {
+		violations = append(violations, *v)
+	}
 
-func (l *Linter) lintCommit(commit git.Commit) error {
-	pattern := `^([\w]+)(?:\(([\w-]+)\))?: (.+)$`
-	re := regexp.MustCompile(pattern)
+	violations = append(violations, secrets...)
+	return append(violations, l.checkCustomRules(commit.Message, header)...)
+}
 
-	matches := re.FindStringSubmatch(commit.Message)
-	if matches == nil {
+// checkHeader validates the first line of a commit message against the
+// built-in rules. It returns the parsed header when the format is valid.
+func (l *Linter) checkHeader(commit git.Commit) (*headerFields, *Violation) {
+	header, scopeViolation := l.parseHeader(commit.Message)
+	if header == nil {
 		// Message format is invalid, try to suggest a correction
 		suggestion, err := l.SuggestMessageCorrection(commit.Message)
 		if err == nil && suggestion != "" {
-			return fmt.Errorf("invalid format. Did you mean: %s", ui.Bold(suggestion))
+			v := errorViolation(RuleHeaderFormat, "invalid format. Did you mean: %s", suggestion)
+			v.Suggestion = suggestion
+			return nil, v
 		}
-		return fmt.Errorf("invalid format")
+		return nil, errorViolation(RuleHeaderFormat, "invalid format")
 	}
 
-	// Rest of the existing validation logic remains the same...
-	commitType := matches[1]
-	scope := matches[2]
-	message := matches[3]
-
 	// Check commit type
 	validType := false
 	for _, t := range l.config.Types {
-		if commitType == t {
+		if header.Type == t {
 			validType = true
 			break
 		}
@@ -217,21 +361,87 @@ func (l *Linter) lintCommit(commit git.Commit) error {
 		// If type is invalid, try to suggest a correction
 		suggestion, err := l.SuggestMessageCorrection(commit.Message)
 		if err == nil && suggestion != "" {
-			return fmt.Errorf("invalid type '%s'. Did you mean: %s", commitType, ui.Bold(suggestion))
+			v := errorViolation(RuleTypeEnum, "invalid type '%s'. Did you mean: %s", header.Type, suggestion)
+			v.Suggestion = suggestion
+			return header, v
 		}
-		return fmt.Errorf("invalid type '%s'", commitType)
+		return header, errorViolation(RuleTypeEnum, "invalid type '%s'", header.Type)
+	}
+
+	// Check emoji prefix
+	if v := l.checkEmoji(header); v != nil {
+		return header, v
+	}
+
+	// Check scope syntax and allowed scopes
+	if scopeViolation != nil {
+		return header, scopeViolation
+	}
+	if v := l.checkScopeEnum(header.Scopes); v != nil {
+		return header, v
 	}
 
 	// Check scope if required
-	if l.config.Rules.RequireScope && scope == "" {
-		return fmt.Errorf("scope is required")
+	if l.config.Rules.RequireScope && len(header.Scopes) == 0 {
+		return header, errorViolation(RuleScopeRequired, "scope is required")
 	}
 
 	// Check message length
-	if len(message) > l.config.Rules.MaxMessageLength {
-		return fmt.Errorf("message too long (%d chars, max %d)",
-			len(message), l.config.Rules.MaxMessageLength)
+	if len(header.Subject) > l.config.Rules.MaxMessageLength {
+		return header, errorViolation(RuleSubjectLength, "message too long (%d chars, max %d)",
+			len(header.Subject), l.config.Rules.MaxMessageLength)
 	}
 
+	return header, nil
+}
+
+var headerPattern = regexp.MustCompile(`^([\w]+)(?:\(([^()]+)\))?: (.+)$`)
+
+// parseHeader splits the first line of a commit message into its parts.
+// It returns nil when the header does not follow the type(scope): subject
+// format, and a violation when the scope syntax is invalid.
+func (l *Linter) parseHeader(message string) (*headerFields, *Violation) {
+	emoji, rest := splitEmojiPrefix(splitMessage(message).Header)
+	matches := headerPattern.FindStringSubmatch(rest)
+	if matches == nil {
+		return nil, nil
+	}
+
+	header := &headerFields{
+		Emoji:   emoji,
+		Type:    matches[1],
+		Scope:   matches[2],
+		Subject: matches[3],
+	}
+
+	scopes, v := l.parseScopes(header.Scope)
+	header.Scopes = scopes
+	return header, v
+}
+
+func errorViolation(rule, format string, args ...interface{}) *Violation {
+	return &Violation{
+		Rule:     rule,
+		Severity: config.SeverityError,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// firstError converts the first error-level violation into an error
+func firstError(violations []Violation) error {
+	for _, v := range violations {
+		if v.Severity == config.SeverityError {
+			return errors.New(v.Message)
+		}
+	}
 	return nil
 }
+
+// printWarnings prints warning-level violations of a single message
+func printWarnings(violations []Violation) {
+	for _, v := range violations {
+		if v.Severity == config.SeverityWarning {
+			ui.Warning(v.styled())
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package linter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// trailerPattern matches git trailer lines such as "Refs: #12" or
+// "BREAKING CHANGE: drop v1 endpoints"
+var trailerPattern = regexp.MustCompile(`^(?:BREAKING CHANGE|[\w-]+)(?:: | #)\S`)
+
+// messageParts holds the sections of a commit message
+type messageParts struct {
+	Header string
+	Body   string
+	Footer string
+}
+
+// splitMessage breaks a commit message into header, body and footer.
+// The footer is the last paragraph when every line in it is a trailer.
+func splitMessage(message string) messageParts {
+	message = strings.TrimSpace(message)
+	header, rest, _ := strings.Cut(message, "\n")
+
+	parts := messageParts{Header: strings.TrimSpace(header)}
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return parts
+	}
+
+	paragraphs := strings.Split(rest, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if isTrailerBlock(last) {
+		parts.Footer = last
+		paragraphs = paragraphs[:len(paragraphs)-1]
+	}
+	parts.Body = strings.TrimSpace(strings.Join(paragraphs, "\n\n"))
+
+	return parts
+}
+
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerPattern.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
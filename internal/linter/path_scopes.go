@@ -0,0 +1,124 @@
+package linter
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/config"
+	"github.com/randilt/git-commit-linter/internal/git"
+)
+
+// RuleScopePathMismatch is reported when a commit's scope does not match
+// the files it changes
+const RuleScopePathMismatch = "scope-path-mismatch"
+
+// uncommittedHash marks a message that has not been committed yet, such as
+// the one checked by the commit-msg hook
+const uncommittedHash = "UNCOMMITTED"
+
+// commitFiles returns the files a commit touches. Messages that are not
+// committed yet are checked against the staged changes, which always
+// needs the git binary.
+func (l *Linter) commitFiles(commit git.Commit) ([]string, error) {
+	if commit.Hash == uncommittedHash {
+		return git.StagedFiles()
+	}
+	return l.repo.ChangedFiles(commit.Hash)
+}
+
+// impliedScopes returns the sorted set of scopes the path_scopes mappings
+// assign to the given files
+func (l *Linter) impliedScopes(files []string) []string {
+	seen := make(map[string]bool)
+	for _, file := range files {
+		for _, m := range l.config.PathScopes.Mappings {
+			for _, pattern := range m.Paths {
+				if matchPath(pattern, file) {
+					seen[m.Scope] = true
+				}
+			}
+		}
+	}
+
+	scopes := make([]string, 0, len(seen))
+	for scope := range seen {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// checkPathScopes verifies that at least one of the commit's scopes is
+// implied by the files it changes. Commits without a scope, or touching
+// only unmapped files, are not checked.
+func (l *Linter) checkPathScopes(commit git.Commit, header *headerFields) *Violation {
+	if len(l.config.PathScopes.Mappings) == 0 || header == nil || len(header.Scopes) == 0 {
+		return nil
+	}
+
+	files, err := l.changedFiles(commit)
+	if err != nil {
+		return &Violation{
+			Rule:     RuleScopePathMismatch,
+			Severity: config.SeverityWarning,
+			Message:  fmt.Sprintf("scope not checked, the changed files could not be listed: %v", err),
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	implied := l.impliedScopes(files)
+	if len(implied) == 0 {
+		return nil
+	}
+
+	for _, scope := range header.Scopes {
+		top := l.scopeSegments(scope)[0]
+		for _, s := range implied {
+			if scope == s || top == s {
+				return nil
+			}
+		}
+	}
+
+	severity := l.config.PathScopes.Severity
+	if severity == "" {
+		severity = config.SeverityWarning
+	}
+	return &Violation{
+		Rule:     RuleScopePathMismatch,
+		Severity: severity,
+		Message: fmt.Sprintf("scope '%s' does not match the changed files. Did you mean: %s",
+			strings.Join(header.Scopes, ", "), strings.Join(implied, ", ")),
+	}
+}
+
+// matchPath reports whether name matches a slash-separated glob pattern in
+// which a "**" segment matches zero or more directories
+func matchPath(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
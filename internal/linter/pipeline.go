@@ -0,0 +1,142 @@
+package linter
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/randilt/git-commit-linter/internal/git"
+)
+
+// commitResult is the outcome of linting one commit of a range
+type commitResult struct {
+	index      int
+	commit     git.Commit
+	violations []Violation
+	// skipped is why the commit was filtered out, if it was
+	skipped string
+}
+
+// SetWorkers sets how many commits are linted in parallel. Zero or less
+// uses one worker per CPU.
+func (l *Linter) SetWorkers(n int) {
+	l.workers = n
+}
+
+// commitStream calls fn for each commit read from the repository, like
+// git.Repository.StreamCommits
+type commitStream func(ctx context.Context, fn func(git.Commit) error) error
+
+// rangeStream streams the commits of a range
+func (l *Linter) rangeStream(commitRange string) commitStream {
+	return func(ctx context.Context, fn func(git.Commit) error) error {
+		return l.repo.StreamCommits(ctx, commitRange, fn)
+	}
+}
+
+// lintRange is lintStream for the commits of a range
+func (l *Linter) lintRange(ctx context.Context, commitRange string, emit func(commitResult)) error {
+	return l.lintStream(ctx, l.rangeStream(commitRange), emit)
+}
+
+// lintStream sends streamed commits through a pool of workers and calls
+// emit with each result in log order. At most a fixed window of commits is
+// in flight, so memory stays flat however long the range is. Commits the
+// filter skips are emitted without being checked, and commits found in the
+// result cache are not checked again.
+func (l *Linter) lintStream(ctx context.Context, stream commitStream, emit func(commitResult)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := l.workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	cache := l.openCache()
+	jobs := make(chan commitResult, workers)
+	results := make(chan commitResult, workers)
+	window := make(chan struct{}, workers*64)
+
+	// streamErr is only read once streamDone is closed
+	var streamErr error
+	streamDone := make(chan struct{})
+	go func() {
+		defer close(streamDone)
+		defer close(jobs)
+		index := 0
+		streamErr = stream(ctx, func(c git.Commit) error {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case jobs <- commitResult{index: index, commit: c}:
+				index++
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if reason := l.skipReason(job.commit); reason != "" {
+					job.skipped = reason
+				} else if violations, ok := cache.lookup(job.commit.Hash); ok {
+					job.violations = violations
+				} else {
+					job.violations = l.checkCommit(job.commit)
+				}
+				select {
+				case results <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Workers finish out of order; hold results back until every earlier
+	// commit has been emitted
+	pending := make(map[int]commitResult)
+	next := 0
+	for result := range results {
+		pending[result.index] = result
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if r.skipped == "" {
+				cache.store(r.commit.Hash, r.violations)
+			}
+			emit(r)
+			next++
+			<-window
+		}
+	}
+
+	<-streamDone
+	if streamErr != nil {
+		return streamErr
+	}
+	// Workers stop early when ctx is cancelled, so results may be missing
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Failing to write the cache only costs time on the next run
+	_ = cache.save()
+	return nil
+}
@@ -0,0 +1,107 @@
+package linter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Scope rule IDs
+const (
+	RuleScopeFormat = "scope-format"
+	RuleScopeEnum   = "scope-enum"
+)
+
+var scopeSegmentPattern = regexp.MustCompile(`^[\w-]+$`)
+
+// parseScopes splits the raw scope of a header into individual scopes using
+// the configured delimiters and checks the syntax of each one
+func (l *Linter) parseScopes(raw string) ([]string, *Violation) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	syntax := l.config.ScopeSyntax
+	parts := []string{raw}
+	for _, d := range syntax.Delimiters {
+		var split []string
+		for _, p := range parts {
+			split = append(split, strings.Split(p, d)...)
+		}
+		parts = split
+	}
+
+	scopes := make([]string, 0, len(parts))
+	for _, p := range parts {
+		scope := strings.TrimSpace(p)
+		for _, segment := range l.scopeSegments(scope) {
+			if !scopeSegmentPattern.MatchString(segment) {
+				return nil, errorViolation(RuleScopeFormat, "invalid scope '%s'", raw)
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+
+	if syntax.MaxScopes > 0 && len(scopes) > syntax.MaxScopes {
+		return scopes, errorViolation(RuleScopeFormat, "too many scopes (%d, max %d)", len(scopes), syntax.MaxScopes)
+	}
+
+	return scopes, nil
+}
+
+// scopeSegments splits a single scope on the configured hierarchy separators
+func (l *Linter) scopeSegments(scope string) []string {
+	segments := []string{scope}
+	for _, sep := range l.config.ScopeSyntax.HierarchySeparators {
+		var split []string
+		for _, s := range segments {
+			split = append(split, strings.Split(s, sep)...)
+		}
+		segments = split
+	}
+	return segments
+}
+
+// checkScopeEnum validates each scope against Config.Scopes. Hierarchical
+// scopes are checked segment by segment: every prefix must be a configured
+// scope (or the parent of one) for as long as the config defines children
+// below it, so with scopes [ui, ui/button] "ui/button/icon" passes and
+// "ui/modal" fails.
+func (l *Linter) checkScopeEnum(scopes []string) *Violation {
+	if len(l.config.Scopes) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(l.config.Scopes))
+	hasChildren := make(map[string]bool)
+	for _, s := range l.config.Scopes {
+		segments := l.scopeSegments(s)
+		allowed[strings.Join(segments, "/")] = true
+		for i := 1; i < len(segments); i++ {
+			hasChildren[strings.Join(segments[:i], "/")] = true
+		}
+	}
+
+	for _, scope := range scopes {
+		segments := l.scopeSegments(scope)
+		for i := range segments {
+			prefix := strings.Join(segments[:i+1], "/")
+			if i > 0 && !hasChildren[strings.Join(segments[:i], "/")] {
+				break
+			}
+			if !allowed[prefix] && !hasChildren[prefix] {
+				return errorViolation(RuleScopeEnum, "invalid scope '%s'%s", scope, l.scopeHint(segments[:i]))
+			}
+		}
+	}
+
+	return nil
+}
+
+// scopeHint lists the allowed scopes below parent for error messages
+func (l *Linter) scopeHint(parent []string) string {
+	if len(parent) > 0 {
+		return fmt.Sprintf(" (unknown segment below '%s')", strings.Join(parent, "/"))
+	}
+	return fmt.Sprintf(", allowed scopes: %s", strings.Join(l.config.Scopes, ", "))
+}
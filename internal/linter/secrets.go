@@ -0,0 +1,135 @@
+package linter
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/config"
+)
+
+// Secret detection rule IDs
+const (
+	RuleSecretAWSKey      = "secret-aws-access-key"
+	RuleSecretGitHubToken = "secret-github-token"
+	RuleSecretPrivateKey  = "secret-private-key"
+	RuleSecretEntropy     = "secret-high-entropy"
+	RuleForbiddenWord     = "forbidden-word"
+)
+
+const (
+	defaultEntropyThreshold = 4.5
+	defaultMinTokenLength   = 20
+)
+
+// secretDetector recognizes a well-known credential format
+type secretDetector struct {
+	rule    string
+	label   string
+	pattern *regexp.Regexp
+}
+
+var secretDetectors = []secretDetector{
+	{
+		rule:    RuleSecretAWSKey,
+		label:   "AWS access key",
+		pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	},
+	{
+		rule:    RuleSecretGitHubToken,
+		label:   "GitHub token",
+		pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`),
+	},
+	{
+		rule:    RuleSecretPrivateKey,
+		label:   "private key",
+		pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`),
+	},
+}
+
+// tokenPattern splits a message into candidate secrets for the entropy
+// check. Slashes end a token, so each part of a file path is checked on its
+// own rather than the path as a whole.
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+=_\-]+`)
+
+// urlPattern matches URLs, whose paths and query strings often look random
+// without being secret. They are left out of the entropy check.
+var urlPattern = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.\-]*://\S+`)
+
+// checkSecrets scans the full commit message for credentials and deny-listed
+// words. Known credential formats and forbidden words are errors, since
+// secrets are hard to scrub from history once pushed. A string that only
+// looks random is reported with entropy_severity, a warning by default.
+func (l *Linter) checkSecrets(message string) []Violation {
+	secrets := &l.config.Secrets
+	if secrets.Disabled {
+		return nil
+	}
+
+	var violations []Violation
+	for _, d := range secretDetectors {
+		if match := d.pattern.FindString(message); match != "" {
+			violations = append(violations, *errorViolation(d.rule,
+				"message contains what looks like a %s (%s)", d.label, redact(match)))
+		}
+	}
+
+	threshold := secrets.EntropyThreshold
+	if threshold == 0 {
+		threshold = defaultEntropyThreshold
+	}
+	minLength := secrets.MinTokenLength
+	if minLength == 0 {
+		minLength = defaultMinTokenLength
+	}
+	severity := secrets.EntropySeverity
+	if severity == "" {
+		severity = config.SeverityWarning
+	}
+	for _, token := range tokenPattern.FindAllString(urlPattern.ReplaceAllString(message, " "), -1) {
+		if len(token) >= minLength && shannonEntropy(token) >= threshold {
+			violations = append(violations, Violation{
+				Rule:     RuleSecretEntropy,
+				Severity: severity,
+				Message:  fmt.Sprintf("message contains a high-entropy string that may be a secret (%s)", redact(token)),
+			})
+			break
+		}
+	}
+
+	for _, word := range secrets.DeniedWords(message) {
+		violations = append(violations, *errorViolation(RuleForbiddenWord,
+			"message contains forbidden word %q", word))
+	}
+
+	return violations
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// redact keeps just enough of a secret to locate it in the message
+func redact(secret string) string {
+	if strings.HasPrefix(secret, "-----") {
+		return secret
+	}
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return fmt.Sprintf("%s%s", secret[:4], strings.Repeat("*", 8))
+}
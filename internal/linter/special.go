@@ -0,0 +1,157 @@
+package linter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/randilt/git-commit-linter/internal/config"
+	"github.com/randilt/git-commit-linter/internal/git"
+)
+
+// Categories of git-generated commit messages
+const (
+	kindMerge      = "merge"
+	kindRevert     = "revert"
+	kindAutosquash = "autosquash"
+)
+
+// RuleSpecialCommit is reported when the policy for a special commit is "fail"
+const RuleSpecialCommit = "special-commit"
+
+var (
+	// mergePattern recognizes git's merge messages when parent hashes are
+	// not available, e.g. inside the commit-msg hook
+	mergePattern      = regexp.MustCompile(`^Merge (?:branch|branches|remote-tracking branch|tag|commit|pull request) `)
+	revertPattern     = regexp.MustCompile(`^Revert "(.+)"$`)
+	autosquashPattern = regexp.MustCompile(`^(fixup|squash|amend)! (.+)$`)
+)
+
+// classifySpecial detects merge, revert and autosquash commits. For reverts
+// and autosquash commits it also returns the message of the commit they
+// refer to; merges are returned unchanged.
+func classifySpecial(commit git.Commit) (kind, original string) {
+	header, rest, _ := strings.Cut(commit.Message, "\n")
+
+	if len(commit.Parents) > 1 || (commit.Parents == nil && mergePattern.MatchString(header)) {
+		return kindMerge, commit.Message
+	}
+
+	if m := revertPattern.FindStringSubmatch(header); m != nil {
+		return kindRevert, m[1]
+	}
+
+	if m := autosquashPattern.FindStringSubmatch(header); m != nil {
+		if m[1] == "amend" && strings.TrimSpace(rest) != "" {
+			// amend! commits carry the replacement message in their body
+			return kindAutosquash, strings.TrimSpace(rest)
+		}
+		return kindAutosquash, m[2]
+	}
+
+	return "", commit.Message
+}
+
+// specialPolicy returns the configured policy for a category, taking branch
+// overrides for the current branch into account
+func (l *Linter) specialPolicy(kind string) string {
+	policy := policyFor(l.config.SpecialCommits.SpecialCommitPolicy, kind)
+
+	if branches := l.config.SpecialCommits.Branches; len(branches) > 0 {
+		current := l.currentBranch()
+		for _, b := range branches {
+			if matched, _ := path.Match(b.Name, current); matched && current != "" {
+				if p := policyFor(b.SpecialCommitPolicy, kind); p != "" {
+					policy = p
+				}
+				break
+			}
+		}
+	}
+
+	if policy != "" {
+		return policy
+	}
+	if kind == kindMerge {
+		return config.PolicyIgnore
+	}
+	return config.PolicyLint
+}
+
+func policyFor(p config.SpecialCommitPolicy, kind string) string {
+	switch kind {
+	case kindMerge:
+		return p.Merge
+	case kindRevert:
+		return p.Revert
+	default:
+		return p.Autosquash
+	}
+}
+
+// SetBranch sets the branch special_commits branch policies are matched
+// against, instead of the checked out branch. Hooks checking a push use the
+// branch being pushed to; an empty name matches no branch policy.
+func (l *Linter) SetBranch(branch string) {
+	l.branchOnce.Do(func() {})
+	l.branch = branch
+}
+
+// currentBranch looks up the checked out branch once per linter
+func (l *Linter) currentBranch() string {
+	l.branchOnce.Do(func() {
+		if l.branch == "" {
+			l.branch, _ = l.repo.CurrentBranch()
+		}
+	})
+	return l.branch
+}
+
+// checkSpecial applies the special commit policy. handled reports whether
+// the commit was fully dealt with; otherwise the returned commit (possibly
+// rewritten to the referenced original) goes through the normal checks.
+func (l *Linter) checkSpecial(commit git.Commit) (git.Commit, []Violation, bool) {
+	for {
+		kind, original := classifySpecial(commit)
+		if kind == "" {
+			return commit, nil, false
+		}
+
+		switch l.specialPolicy(kind) {
+		case config.PolicyIgnore:
+			return commit, nil, true
+		case config.PolicyFail:
+			var branch string
+			if len(l.config.SpecialCommits.Branches) > 0 {
+				branch = l.currentBranch()
+			}
+			return commit, []Violation{*errorViolation(RuleSpecialCommit, "%s", specialFailure(kind, branch))}, true
+		}
+
+		if kind == kindMerge {
+			return commit, nil, false
+		}
+
+		// Lint the referenced message, unwrapping nested reverts and fixups.
+		// Its parents are unknown, so merges are detected by message.
+		commit.Message = original
+		commit.Parents = nil
+	}
+}
+
+func specialFailure(kind, branch string) string {
+	var what string
+	switch kind {
+	case kindMerge:
+		what = "merge commits are"
+	case kindRevert:
+		what = "revert commits are"
+	default:
+		what = "fixup!/squash!/amend! commits are"
+	}
+	if branch != "" {
+		return fmt.Sprintf("%s not allowed on branch %s", what, branch)
+	}
+	return what + " not allowed"
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v2"
 )
@@ -56,6 +57,21 @@ func LoadKeywords() (*KeywordsConfig, error) {
 	return &config, nil
 }
 
+var (
+	keywordsOnce   sync.Once
+	cachedKeywords *KeywordsConfig
+	keywordsErr    error
+)
+
+// loadKeywordsOnce reads the keywords file the first time it is needed, so
+// linting a long range does not parse it again for every bad commit
+func loadKeywordsOnce() (*KeywordsConfig, error) {
+	keywordsOnce.Do(func() {
+		cachedKeywords, keywordsErr = LoadKeywords()
+	})
+	return cachedKeywords, keywordsErr
+}
+
 // SuggestCorrection analyzes an invalid commit message and suggests corrections
 func SuggestCorrection(message string, config *KeywordsConfig) (*CommitCorrection, error) {
 	// Clean the message
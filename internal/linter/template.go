@@ -0,0 +1,130 @@
+package linter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// issueKeyPattern finds tracker keys such as "PROJ-12" in branch names
+var issueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b`)
+
+// branchWordSeparators splits a branch name into the words the keyword
+// engine scores
+var branchWordSeparators = strings.NewReplacer("/", " ", "-", " ", "_", " ", ".", " ")
+
+// CommitTemplate returns the text the prepare-commit-msg hook puts in the
+// editor: a header with the type and scope guessed from the branch name
+// and the staged files, a "Refs:" footer for an issue key in the branch
+// name, and comments listing what the config allows. Parts that cannot be
+// guessed are left out. The comments use the comment character set with
+// SetCleanup.
+//
+// For the branch "feat/PROJ-12-login", with the keywords file mapping
+// "login" to the auth scope, the template starts with:
+//
+//	feat(auth):
+//
+//	Refs: PROJ-12
+func (l *Linter) CommitTemplate(branch string, files []string) string {
+	var b strings.Builder
+	header := l.templateType(branch)
+	if header != "" {
+		if scope := l.templateScope(branch, files); scope != "" {
+			header += "(" + scope + ")"
+		}
+		header += ": "
+	}
+	b.WriteString(header + "\n")
+
+	if key := issueKeyPattern.FindString(branch); key != "" {
+		b.WriteString("\nRefs: " + key + "\n")
+	}
+
+	comment := l.cleanup.CommentPrefix("")
+	b.WriteString(fmt.Sprintf("\n%s Format: type(scope): subject\n", comment))
+	b.WriteString(fmt.Sprintf("%s Allowed types: %s\n", comment, strings.Join(l.config.Types, ", ")))
+	switch {
+	case len(l.config.Scopes) > 0 && l.config.Rules.RequireScope:
+		b.WriteString(fmt.Sprintf("%s Allowed scopes (required): %s\n", comment, strings.Join(l.config.Scopes, ", ")))
+	case len(l.config.Scopes) > 0:
+		b.WriteString(fmt.Sprintf("%s Allowed scopes: %s\n", comment, strings.Join(l.config.Scopes, ", ")))
+	case l.config.Rules.RequireScope:
+		b.WriteString(comment + " A scope is required\n")
+	}
+	if l.config.Rules.MaxMessageLength > 0 {
+		b.WriteString(fmt.Sprintf("%s Keep the subject within %d characters\n", comment, l.config.Rules.MaxMessageLength))
+	}
+	return b.String()
+}
+
+// templateType guesses the commit type from the branch name: its first
+// segment if that is a configured type, as in "fix/login", or else the
+// type the keyword engine scores highest, as in "feature/login"
+func (l *Linter) templateType(branch string) string {
+	prefix, _, _ := strings.Cut(branch, "/")
+	if l.allowedType(prefix) {
+		return prefix
+	}
+
+	keywords, err := loadKeywordsOnce()
+	if err != nil || branch == "" {
+		return ""
+	}
+	correction, err := SuggestCorrection(branchWordSeparators.Replace(branch), keywords)
+	if err != nil || correction.Score == 0 || !l.allowedType(correction.Type) {
+		return ""
+	}
+	return correction.Type
+}
+
+func (l *Linter) allowedType(commitType string) bool {
+	for _, t := range l.config.Types {
+		if commitType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// templateScope guesses the scope from the path_scopes mappings of the
+// staged files, falling back to the keyword engine on the branch name.
+// Scopes the config does not allow are dropped.
+func (l *Linter) templateScope(branch string, files []string) string {
+	implied := l.impliedScopes(files)
+	syntax := l.config.ScopeSyntax
+	switch {
+	case len(implied) == 1:
+		return l.allowedScope(implied[0])
+	case len(implied) > 1 && len(syntax.Delimiters) > 0 &&
+		(syntax.MaxScopes == 0 || len(implied) <= syntax.MaxScopes):
+		if l.checkScopeEnum(implied) == nil {
+			return strings.Join(implied, syntax.Delimiters[0])
+		}
+		return ""
+	case len(implied) > 1:
+		return ""
+	}
+
+	keywords, err := loadKeywordsOnce()
+	if err != nil || branch == "" {
+		return ""
+	}
+	// The type prefix and the issue key only add noise to the scope scores
+	words := issueKeyPattern.ReplaceAllString(branch, "")
+	if _, rest, ok := strings.Cut(words, "/"); ok {
+		words = rest
+	}
+	correction, err := SuggestCorrection(branchWordSeparators.Replace(words), keywords)
+	if err != nil {
+		return ""
+	}
+	return l.allowedScope(correction.Scope)
+}
+
+func (l *Linter) allowedScope(scope string) string {
+	if scope == "" || l.checkScopeEnum([]string{scope}) != nil {
+		return ""
+	}
+	return scope
+}
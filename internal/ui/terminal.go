@@ -0,0 +1,379 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ErrInterrupted is returned by prompts when the user presses Ctrl-C or
+// Ctrl-D, or input ends
+var ErrInterrupted = errors.New("interrupted")
+
+// errNoRawMode is returned by makeRaw on platforms where keys cannot be
+// read one at a time
+var errNoRawMode = errors.New("raw terminal mode is not supported on this platform")
+
+// defaultWidth is the terminal width assumed when it cannot be read
+const defaultWidth = 80
+
+// pageSize is the number of options Select shows at once
+const pageSize = 10
+
+// Keys readKey reports besides text
+const (
+	keyNone = iota
+	keyText
+	keyUp
+	keyDown
+	keyEnter
+	keyBackspace
+	keyClear
+	keyInterrupt
+)
+
+// Option is one of the choices Select offers
+type Option struct {
+	Value       string
+	Description string
+}
+
+// Terminal asks questions on an interactive terminal. Keys are read one
+// at a time, so choices are made with the arrow keys; where the platform
+// has no raw mode, prompts fall back to reading whole lines.
+type Terminal struct {
+	in     *os.File
+	out    io.Writer
+	reader *bufio.Reader
+}
+
+// NewTerminal returns a terminal on standard input and output, or an
+// error when standard input is not a terminal
+func NewTerminal() (*Terminal, error) {
+	fd := os.Stdin.Fd()
+	if !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd) {
+		return nil, errors.New("standard input is not a terminal")
+	}
+	return &Terminal{in: os.Stdin, out: os.Stdout, reader: bufio.NewReader(os.Stdin)}, nil
+}
+
+// Select asks the user to pick one of options, starting at selected, and
+// returns the index picked
+func (t *Terminal) Select(label string, options []Option, selected int) (int, error) {
+	restore, err := makeRaw(int(t.in.Fd()))
+	if err != nil {
+		return t.selectLine(label, options, selected)
+	}
+	defer restore()
+
+	width := terminalWidth(int(t.in.Fd()))
+	shown := min(len(options), pageSize)
+	fmt.Fprintf(t.out, "%s %s %s\n", infoSymbol, boldText(label), dimText("(↑/↓ to move, enter to select)"))
+	t.renderOptions(options, selected, width)
+	for {
+		key, _, err := t.readKey()
+		if err != nil {
+			return 0, err
+		}
+		switch key {
+		case keyUp:
+			selected = (selected - 1 + len(options)) % len(options)
+		case keyDown:
+			selected = (selected + 1) % len(options)
+		case keyEnter:
+			t.clearLines(shown + 1)
+			fmt.Fprintf(t.out, "%s %s %s\n", successSymbol, boldText(label), options[selected].Value)
+			return selected, nil
+		case keyInterrupt:
+			t.clearLines(shown + 1)
+			return 0, ErrInterrupted
+		default:
+			continue
+		}
+		t.clearLines(shown)
+		t.renderOptions(options, selected, width)
+	}
+}
+
+// renderOptions prints the page of options around the selected one
+func (t *Terminal) renderOptions(options []Option, selected, width int) {
+	first := 0
+	if len(options) > pageSize {
+		first = min(max(selected-pageSize/2, 0), len(options)-pageSize)
+	}
+	for i := first; i < first+min(len(options), pageSize); i++ {
+		line := truncate(fmt.Sprintf("  %-12s %s", options[i].Value, options[i].Description), width-3)
+		if i == selected {
+			fmt.Fprintf(t.out, "%s %s\n", infoText("❯"), boldText(line))
+		} else {
+			fmt.Fprintf(t.out, "  %s\n", dimText(line))
+		}
+	}
+}
+
+// Input reads a line of text, starting from initial. check is called with
+// the text after every key; the hint it returns is shown under the line,
+// and an error is shown instead and keeps Enter from accepting the text.
+func (t *Terminal) Input(label, initial string, check func(string) (string, error)) (string, error) {
+	restore, err := makeRaw(int(t.in.Fd()))
+	if err != nil {
+		return t.inputLine(label, initial, check)
+	}
+	defer restore()
+
+	width := terminalWidth(int(t.in.Fd()))
+	prompt := fmt.Sprintf("%s %s ", infoSymbol, boldText(label+":"))
+	room := width - utf8.RuneCountInString(label) - 4
+	text := initial
+	for {
+		status := ""
+		hint, checkErr := check(text)
+		if checkErr != nil {
+			status = errorText(truncate(checkErr.Error(), width-1))
+		} else if hint != "" {
+			status = dimText(truncate(hint, width-1))
+		}
+		// Draw the status line first, so the cursor ends after the text
+		fmt.Fprintf(t.out, "\r\x1b[2K\n\x1b[2K%s\x1b[1A\r\x1b[2K%s%s", status, prompt, tail(text, room))
+
+		key, typed, err := t.readKey()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case keyText:
+			text += typed
+		case keyBackspace:
+			if _, size := utf8.DecodeLastRuneInString(text); size > 0 {
+				text = text[:len(text)-size]
+			}
+		case keyClear:
+			text = ""
+		case keyEnter:
+			if checkErr != nil {
+				continue
+			}
+			fmt.Fprintf(t.out, "\r\x1b[2K\n\x1b[2K\x1b[1A\r%s %s %s\n", successSymbol, boldText(label+":"), text)
+			return text, nil
+		case keyInterrupt:
+			fmt.Fprint(t.out, "\r\x1b[2K\n\x1b[2K\x1b[1A\r")
+			return "", ErrInterrupted
+		}
+	}
+}
+
+// Lines reads text of several lines, one Input per line, until an empty
+// line. The lines of initial are offered in turn. check is called with the
+// lines so far joined by newlines, the one being typed included.
+func (t *Terminal) Lines(label, initial string, check func(string) (string, error)) (string, error) {
+	var lines, offered []string
+	if initial = strings.TrimSpace(initial); initial != "" {
+		offered = strings.Split(initial, "\n")
+	}
+	for {
+		next := ""
+		if len(lines) < len(offered) {
+			next = offered[len(lines)]
+		}
+		lineLabel := label
+		if len(lines) > 0 {
+			lineLabel = fmt.Sprintf("%*s", utf8.RuneCountInString(label), "")
+		}
+		line, err := t.Input(lineLabel, next, func(line string) (string, error) {
+			return check(strings.Join(append(lines[:len(lines):len(lines)], line), "\n"))
+		})
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(line) == "" {
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// Confirm asks a yes or no question, with the answer Enter gives
+func (t *Terminal) Confirm(label string, yes bool) (bool, error) {
+	choices := "[y/N]"
+	if yes {
+		choices = "[Y/n]"
+	}
+	restore, err := makeRaw(int(t.in.Fd()))
+	if err != nil {
+		return t.confirmLine(label, choices, yes)
+	}
+	defer restore()
+
+	fmt.Fprintf(t.out, "%s %s %s ", infoSymbol, boldText(label), dimText(choices))
+	for {
+		key, typed, err := t.readKey()
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case key == keyInterrupt:
+			fmt.Fprintln(t.out)
+			return false, ErrInterrupted
+		case key == keyEnter:
+		case key == keyText && strings.EqualFold(typed, "y"):
+			yes = true
+		case key == keyText && strings.EqualFold(typed, "n"):
+			yes = false
+		default:
+			continue
+		}
+		answer := "no"
+		if yes {
+			answer = "yes"
+		}
+		fmt.Fprintf(t.out, "\r\x1b[2K%s %s %s\n", successSymbol, boldText(label), answer)
+		return yes, nil
+	}
+}
+
+// readKey reads one key press, or the text of a paste, in raw mode
+func (t *Terminal) readKey() (int, string, error) {
+	buf := make([]byte, 256)
+	n, err := t.in.Read(buf)
+	if err != nil || n == 0 {
+		return keyInterrupt, "", ErrInterrupted
+	}
+	input := string(buf[:n])
+
+	switch input {
+	case "\x1b[A", "\x1bOA":
+		return keyUp, "", nil
+	case "\x1b[B", "\x1bOB":
+		return keyDown, "", nil
+	case "\r", "\n":
+		return keyEnter, "", nil
+	case "\x7f", "\b":
+		return keyBackspace, "", nil
+	case "\x15":
+		return keyClear, "", nil
+	case "\x03", "\x04":
+		return keyInterrupt, "", nil
+	}
+	if strings.HasPrefix(input, "\x1b") {
+		return keyNone, "", nil
+	}
+
+	// Pasted text keeps its printable characters, on one line
+	text := strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return ' '
+		case !unicode.IsPrint(r):
+			return -1
+		}
+		return r
+	}, input)
+	if text == "" {
+		return keyNone, "", nil
+	}
+	return keyText, text, nil
+}
+
+// clearLines moves the cursor up over the last n lines and clears them
+func (t *Terminal) clearLines(n int) {
+	for i := 0; i < n; i++ {
+		fmt.Fprint(t.out, "\x1b[1A\r\x1b[2K")
+	}
+}
+
+// selectLine is Select for terminals without raw mode: the options are
+// numbered and the user types a number
+func (t *Terminal) selectLine(label string, options []Option, selected int) (int, error) {
+	fmt.Fprintf(t.out, "%s %s\n", infoSymbol, boldText(label))
+	for i, option := range options {
+		fmt.Fprintf(t.out, "  %2d) %-12s %s\n", i+1, option.Value, dimText(option.Description))
+	}
+	for {
+		fmt.Fprintf(t.out, "%s ", boldText(fmt.Sprintf("Choose 1-%d [%d]:", len(options), selected+1)))
+		line, err := t.readLine()
+		if err != nil {
+			return 0, err
+		}
+		if line == "" {
+			return selected, nil
+		}
+		if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+	}
+}
+
+// inputLine is Input for terminals without raw mode: text is checked
+// after Enter and asked for again while it fails
+func (t *Terminal) inputLine(label, initial string, check func(string) (string, error)) (string, error) {
+	for {
+		if initial != "" {
+			fmt.Fprintf(t.out, "%s %s ", boldText(label+":"), dimText("["+initial+"]"))
+		} else {
+			fmt.Fprintf(t.out, "%s ", boldText(label+":"))
+		}
+		text, err := t.readLine()
+		if err != nil {
+			return "", err
+		}
+		if text == "" {
+			text = initial
+		}
+		if _, err := check(text); err != nil {
+			Error(err.Error())
+			continue
+		}
+		return text, nil
+	}
+}
+
+// confirmLine is Confirm for terminals without raw mode
+func (t *Terminal) confirmLine(label, choices string, yes bool) (bool, error) {
+	fmt.Fprintf(t.out, "%s %s ", boldText(label), dimText(choices))
+	answer, err := t.readLine()
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return yes, nil
+}
+
+func (t *Terminal) readLine() (string, error) {
+	line, err := t.reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", ErrInterrupted
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// truncate shortens text to at most width characters
+func truncate(text string, width int) string {
+	if width < 1 || utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:width-1]) + "…"
+}
+
+// tail returns the end of text that fits in width characters, so the
+// cursor stays on screen while long text is typed
+func tail(text string, width int) string {
+	if width < 2 || utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	return "…" + string(runes[len(runes)-width+1:])
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package ui
+
+// makeRaw is not available here, so prompts read whole lines
+func makeRaw(fd int) (func(), error) {
+	return nil, errNoRawMode
+}
+
+func terminalWidth(fd int) int {
+	return defaultWidth
+}